@@ -0,0 +1,87 @@
+package oauth2cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+// acceptTimeoutListener is a listener which waits for a connection up to the timeout at a time.
+// On each timeout, it checks whether the context is done and then waits again.
+//
+// listener.Listener does not expose SetDeadline of the underlying listener,
+// so the connections are accepted in the background and Accept waits for them with a timer.
+type acceptTimeoutListener struct {
+	net.Listener
+	ctx     context.Context
+	timeout time.Duration
+	logger  *slog.Logger
+
+	startOnce sync.Once
+	acceptCh  chan acceptResult
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+type acceptResult struct {
+	conn net.Conn
+	err  error
+}
+
+func newAcceptTimeoutListener(ctx context.Context, l net.Listener, timeout time.Duration, logger *slog.Logger) *acceptTimeoutListener {
+	return &acceptTimeoutListener{
+		Listener: l,
+		ctx:      ctx,
+		timeout:  timeout,
+		logger:   logger,
+		acceptCh: make(chan acceptResult),
+		closed:   make(chan struct{}),
+	}
+}
+
+func (l *acceptTimeoutListener) acceptLoop() {
+	for {
+		conn, err := l.Listener.Accept()
+		select {
+		case l.acceptCh <- acceptResult{conn, err}:
+		case <-l.closed:
+			if conn != nil {
+				_ = conn.Close()
+			}
+			return
+		}
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+	}
+}
+
+func (l *acceptTimeoutListener) Accept() (net.Conn, error) {
+	l.startOnce.Do(func() { go l.acceptLoop() })
+	for {
+		timer := time.NewTimer(l.timeout)
+		select {
+		case r := <-l.acceptCh:
+			timer.Stop()
+			return r.conn, r.err
+		case <-l.closed:
+			timer.Stop()
+			return nil, net.ErrClosed
+		case <-timer.C:
+			if err := l.ctx.Err(); err != nil {
+				return nil, fmt.Errorf("context done while waiting for a connection: %w", err)
+			}
+			l.logger.Debug("no connection to the local server within the accept timeout",
+				slog.Duration("timeout", l.timeout))
+		}
+	}
+}
+
+func (l *acceptTimeoutListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return l.Listener.Close()
+}
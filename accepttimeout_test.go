@@ -0,0 +1,63 @@
+package oauth2cli
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestAcceptTimeoutListener(t *testing.T) {
+	t.Run("Accept", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("could not listen: %s", err)
+		}
+		tl := newAcceptTimeoutListener(context.TODO(), l, 10*time.Millisecond, discardLogger)
+		defer tl.Close()
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			conn, err := net.Dial("tcp", l.Addr().String())
+			if err != nil {
+				t.Errorf("could not dial: %s", err)
+				return
+			}
+			_ = conn.Close()
+		}()
+		conn, err := tl.Accept()
+		if err != nil {
+			t.Fatalf("Accept error: %s", err)
+		}
+		_ = conn.Close()
+	})
+
+	t.Run("ContextDone", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("could not listen: %s", err)
+		}
+		ctx, cancel := context.WithCancel(context.TODO())
+		tl := newAcceptTimeoutListener(ctx, l, 10*time.Millisecond, discardLogger)
+		defer tl.Close()
+		cancel()
+		if _, err := tl.Accept(); !errors.Is(err, context.Canceled) {
+			t.Errorf("Accept wants context.Canceled but was %v", err)
+		}
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("could not listen: %s", err)
+		}
+		tl := newAcceptTimeoutListener(context.TODO(), l, time.Second, discardLogger)
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			_ = tl.Close()
+		}()
+		if _, err := tl.Accept(); !errors.Is(err, net.ErrClosed) {
+			t.Errorf("Accept wants net.ErrClosed but was %v", err)
+		}
+	})
+}
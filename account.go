@@ -0,0 +1,172 @@
+package oauth2cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// AccountRegistry manages the configs and tokens of multiple accounts,
+// e.g. for a CLI tool with profiles.
+// The zero value is ready to use. It is safe for concurrent use.
+type AccountRegistry struct {
+	// Cache of the tokens of the accounts.
+	// The key of a token is the account ID.
+	// Default to none, i.e. GetToken always performs the flow.
+	TokenCache TokenCache
+
+	mu             sync.Mutex
+	accounts       map[string]Account
+	defaultAccount string
+}
+
+// Account represents an account in AccountRegistry.
+type Account struct {
+	Config Config
+
+	// Cache of the token of the account.
+	// Default to AccountRegistry.TokenCache.
+	TokenCache TokenCache
+}
+
+func (a Account) tokenCache(r *AccountRegistry) TokenCache {
+	if a.TokenCache != nil {
+		return a.TokenCache
+	}
+	return r.TokenCache
+}
+
+// Register adds the account with the config.
+// The first account becomes the default account.
+func (r *AccountRegistry) Register(accountID string, cfg Config) error {
+	return r.RegisterAccount(accountID, Account{Config: cfg})
+}
+
+// RegisterAccount adds the account.
+// The first account becomes the default account.
+func (r *AccountRegistry) RegisterAccount(accountID string, account Account) error {
+	if accountID == "" {
+		return errors.New("account ID must not be empty")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.accounts[accountID]; ok {
+		return fmt.Errorf("account %s is already registered", accountID)
+	}
+	if r.accounts == nil {
+		r.accounts = make(map[string]Account)
+	}
+	r.accounts[accountID] = account
+	if r.defaultAccount == "" {
+		r.defaultAccount = accountID
+	}
+	return nil
+}
+
+// GetToken returns a token of the account.
+// If accountID is empty, it returns a token of the default account.
+//
+// It returns the cached token if available, or refreshes it.
+// If the cache has no token, or the token has expired and cannot be refreshed,
+// it performs GetToken with the config of the account and saves the token to the cache.
+// It returns an error if the cache could not be read or the token endpoint is not available.
+func (r *AccountRegistry) GetToken(ctx context.Context, accountID string) (*oauth2.Token, error) {
+	r.mu.Lock()
+	if accountID == "" {
+		accountID = r.defaultAccount
+	}
+	account, ok := r.accounts[accountID]
+	cache := account.tokenCache(r)
+	r.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("account %q is not registered", accountID)
+	}
+	cfg := account.Config
+	if cache == nil {
+		return GetToken(ctx, cfg)
+	}
+	token, err := cache.Load(accountID)
+	if err != nil {
+		return nil, fmt.Errorf("could not load the token from the cache: %w", err)
+	}
+	if token != nil && (token.RefreshToken != "" || isTokenFresh(token, cfg.tokenExpirySlack())) {
+		newToken, err := NewCachedTokenSource(ctx, cfg, token).Token()
+		if err == nil {
+			if newToken != token {
+				if err := cache.Save(accountID, newToken); err != nil {
+					cfg.logger().Warn("could not save the refreshed token to the cache", "account", accountID, "error", err)
+				}
+			}
+			return newToken, nil
+		}
+		// fall back to the flow only if the refresh token is rejected, e.g. expired or revoked
+		var retrieveErr *oauth2.RetrieveError
+		if !errors.As(err, &retrieveErr) || retrieveErr.Response == nil || retrieveErr.Response.StatusCode/100 != 4 {
+			return nil, err
+		}
+	}
+	token, err = GetToken(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := cache.Save(accountID, token); err != nil {
+		cfg.logger().Warn("could not save the token to the cache", "account", accountID, "error", err)
+	}
+	return token, nil
+}
+
+// ListAccounts returns the IDs of the accounts in order.
+func (r *AccountRegistry) ListAccounts() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]string, 0, len(r.accounts))
+	for id := range r.accounts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// RemoveAccount removes the account and its token in the cache.
+// If it is the default account, no account becomes the default.
+func (r *AccountRegistry) RemoveAccount(accountID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	account, ok := r.accounts[accountID]
+	if !ok {
+		return fmt.Errorf("account %q is not registered", accountID)
+	}
+	if cache := account.tokenCache(r); cache != nil {
+		if err := cache.Remove(accountID); err != nil {
+			return fmt.Errorf("could not remove the token from the cache: %w", err)
+		}
+	}
+	delete(r.accounts, accountID)
+	if r.defaultAccount == accountID {
+		r.defaultAccount = ""
+	}
+	return nil
+}
+
+// DefaultAccount returns the ID of the default account.
+// It returns an empty string if no account is the default.
+func (r *AccountRegistry) DefaultAccount() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.defaultAccount
+}
+
+// SetDefaultAccount sets the default account.
+func (r *AccountRegistry) SetDefaultAccount(accountID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.accounts[accountID]; !ok {
+		return fmt.Errorf("account %q is not registered", accountID)
+	}
+	r.defaultAccount = accountID
+	return nil
+}
@@ -0,0 +1,211 @@
+package oauth2cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/oauth2"
+)
+
+func TestAccountRegistry(t *testing.T) {
+	cache := memoryTokenCache{
+		"alice": {AccessToken: "ALICE_TOKEN", Expiry: time.Now().Add(time.Hour)},
+		"bob":   {AccessToken: "BOB_TOKEN", Expiry: time.Now().Add(time.Hour)},
+	}
+	var r AccountRegistry
+	r.TokenCache = cache
+	for _, id := range []string{"bob", "alice"} {
+		if err := r.Register(id, Config{}); err != nil {
+			t.Fatalf("Register error: %s", err)
+		}
+	}
+	if err := r.Register("alice", Config{}); err == nil {
+		t.Errorf("Register wants error on the duplicate account but was nil")
+	}
+	if diff := cmp.Diff([]string{"alice", "bob"}, r.ListAccounts()); diff != "" {
+		t.Errorf("ListAccounts mismatch (-want +got):\n%s", diff)
+	}
+
+	t.Run("DefaultAccount", func(t *testing.T) {
+		if w := "bob"; r.DefaultAccount() != w {
+			t.Errorf("DefaultAccount wants %s but was %s", w, r.DefaultAccount())
+		}
+		token, err := r.GetToken(context.TODO(), "")
+		if err != nil {
+			t.Fatalf("GetToken error: %s", err)
+		}
+		if w := "BOB_TOKEN"; token.AccessToken != w {
+			t.Errorf("AccessToken wants %s but was %s", w, token.AccessToken)
+		}
+		if err := r.SetDefaultAccount("alice"); err != nil {
+			t.Fatalf("SetDefaultAccount error: %s", err)
+		}
+		token, err = r.GetToken(context.TODO(), "")
+		if err != nil {
+			t.Fatalf("GetToken error: %s", err)
+		}
+		if w := "ALICE_TOKEN"; token.AccessToken != w {
+			t.Errorf("AccessToken wants %s but was %s", w, token.AccessToken)
+		}
+		if err := r.SetDefaultAccount("carol"); err == nil {
+			t.Errorf("SetDefaultAccount wants error but was nil")
+		}
+	})
+
+	t.Run("RemoveAccount", func(t *testing.T) {
+		if err := r.RemoveAccount("alice"); err != nil {
+			t.Fatalf("RemoveAccount error: %s", err)
+		}
+		if _, ok := cache["alice"]; ok {
+			t.Errorf("token of the account wants to be removed")
+		}
+		if r.DefaultAccount() != "" {
+			t.Errorf("DefaultAccount wants empty but was %s", r.DefaultAccount())
+		}
+		if _, err := r.GetToken(context.TODO(), "alice"); err == nil {
+			t.Errorf("GetToken wants error but was nil")
+		}
+		if err := r.RemoveAccount("alice"); err == nil {
+			t.Errorf("RemoveAccount wants error but was nil")
+		}
+	})
+}
+
+func TestAccountRegistry_GetTokenAndSave(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth":
+			q := url.Values{"code": {"AUTH_CODE"}, "state": {r.FormValue("state")}}
+			http.Redirect(w, r, r.FormValue("redirect_uri")+"?"+q.Encode(), 302)
+		case "/token":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprint(w, `{"access_token":"ACCESS_TOKEN","token_type":"Bearer","expires_in":3600}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+	cache := memoryTokenCache{}
+	r := AccountRegistry{TokenCache: cache}
+	if err := r.Register("alice", Config{
+		OAuth2Config: oauth2.Config{
+			ClientID: "YOUR_CLIENT_ID",
+			Endpoint: oauth2.Endpoint{AuthURL: s.URL + "/auth", TokenURL: s.URL + "/token", AuthStyle: oauth2.AuthStyleInParams},
+		},
+		BrowserOpener: BrowserOpenerFunc(func(u string) error {
+			resp, err := http.Get(u)
+			if err != nil {
+				return err
+			}
+			return resp.Body.Close()
+		}),
+	}); err != nil {
+		t.Fatalf("Register error: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+	token, err := r.GetToken(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetToken error: %s", err)
+	}
+	if w := "ACCESS_TOKEN"; token.AccessToken != w {
+		t.Errorf("AccessToken wants %s but was %s", w, token.AccessToken)
+	}
+	if cache["alice"] != token {
+		t.Errorf("cache wants the token but was %+v", cache["alice"])
+	}
+}
+
+type errorTokenCache struct{ err error }
+
+func (c errorTokenCache) Load(string) (*oauth2.Token, error) { return nil, c.err }
+func (c errorTokenCache) Save(string, *oauth2.Token) error   { return c.err }
+func (c errorTokenCache) Remove(string) error                { return c.err }
+
+func TestAccountRegistry_GetTokenFallback(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth":
+			q := url.Values{"code": {"AUTH_CODE"}, "state": {r.FormValue("state")}}
+			http.Redirect(w, r, r.FormValue("redirect_uri")+"?"+q.Encode(), 302)
+		case "/token":
+			w.Header().Set("Content-Type", "application/json")
+			switch r.FormValue("refresh_token") {
+			case "REVOKED_REFRESH_TOKEN":
+				w.WriteHeader(400)
+				_, _ = fmt.Fprint(w, `{"error":"invalid_grant"}`)
+			case "REFRESH_TOKEN":
+				w.WriteHeader(503)
+				_, _ = fmt.Fprint(w, `{"error":"temporarily_unavailable"}`)
+			default:
+				_, _ = fmt.Fprint(w, `{"access_token":"ACCESS_TOKEN","token_type":"Bearer","expires_in":3600}`)
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+	var browserCalls int
+	cfg := Config{
+		OAuth2Config: oauth2.Config{
+			ClientID: "YOUR_CLIENT_ID",
+			Endpoint: oauth2.Endpoint{AuthURL: s.URL + "/auth", TokenURL: s.URL + "/token", AuthStyle: oauth2.AuthStyleInParams},
+		},
+		BrowserOpener: BrowserOpenerFunc(func(u string) error {
+			browserCalls++
+			resp, err := http.Get(u)
+			if err != nil {
+				return err
+			}
+			return resp.Body.Close()
+		}),
+	}
+	expired := time.Now().Add(-time.Hour)
+
+	for _, c := range []struct {
+		name        string
+		cache       TokenCache
+		wantBrowser bool
+		wantErr     bool
+	}{
+		{"NotFound", memoryTokenCache{}, true, false},
+		{"RefreshTokenRejected", memoryTokenCache{"alice": {AccessToken: "OLD", RefreshToken: "REVOKED_REFRESH_TOKEN", Expiry: expired}}, true, false},
+		{"NoRefreshToken", memoryTokenCache{"alice": {AccessToken: "OLD", Expiry: expired}}, true, false},
+		{"TokenEndpointUnavailable", memoryTokenCache{"alice": {AccessToken: "OLD", RefreshToken: "REFRESH_TOKEN", Expiry: expired}}, false, true},
+		{"LoadError", errorTokenCache{errors.New("keyring is locked")}, false, true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			browserCalls = 0
+			// the registry has no cache, so the cache of the account is used
+			var r AccountRegistry
+			if err := r.RegisterAccount("alice", Account{Config: cfg, TokenCache: c.cache}); err != nil {
+				t.Fatalf("RegisterAccount error: %s", err)
+			}
+			ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+			defer cancel()
+			token, err := r.GetToken(ctx, "alice")
+			if c.wantErr {
+				if err == nil {
+					t.Errorf("GetToken wants error but was %+v", token)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("GetToken error: %s", err)
+				}
+				if mc := c.cache.(memoryTokenCache); mc["alice"] != token {
+					t.Errorf("cache of the account wants the token but was %+v", mc["alice"])
+				}
+			}
+			if gotBrowser := browserCalls > 0; gotBrowser != c.wantBrowser {
+				t.Errorf("browser wants %v but was %v", c.wantBrowser, gotBrowser)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+// Package adapters provides conversion between oauth2.Token and the token formats of providers.
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// TokenAdapter converts a token to and from the format of a provider.
+type TokenAdapter interface {
+	ToProviderFormat(t *oauth2.Token) ([]byte, error)
+	FromProviderFormat(data []byte) (*oauth2.Token, error)
+}
+
+var (
+	_ TokenAdapter = GoogleTokenAdapter{}
+	_ TokenAdapter = AzureTokenAdapter{}
+	_ TokenAdapter = OktaTokenAdapter{}
+)
+
+var now = time.Now
+
+func extraString(t *oauth2.Token, key string) string {
+	s, _ := t.Extra(key).(string)
+	return s
+}
+
+// GoogleTokenAdapter converts a token in the format of Google.
+// It accepts either an absolute expiry_time (RFC 3339) or a relative expires_in,
+// and writes both of them.
+type GoogleTokenAdapter struct{}
+
+type googleToken struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+	ExpiryTime   string `json:"expiry_time,omitempty"`
+}
+
+// ToProviderFormat converts the token to the format of Google.
+func (GoogleTokenAdapter) ToProviderFormat(t *oauth2.Token) ([]byte, error) {
+	g := googleToken{
+		AccessToken:  t.AccessToken,
+		TokenType:    t.TokenType,
+		RefreshToken: t.RefreshToken,
+		IDToken:      extraString(t, "id_token"),
+		Scope:        extraString(t, "scope"),
+	}
+	if !t.Expiry.IsZero() {
+		g.ExpiryTime = t.Expiry.UTC().Format(time.RFC3339)
+		if expiresIn := int64(t.Expiry.Sub(now()).Seconds()); expiresIn > 0 {
+			g.ExpiresIn = expiresIn
+		}
+	}
+	return json.Marshal(&g)
+}
+
+// FromProviderFormat converts the token in the format of Google.
+func (GoogleTokenAdapter) FromProviderFormat(data []byte) (*oauth2.Token, error) {
+	var g googleToken
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	t := &oauth2.Token{
+		AccessToken:  g.AccessToken,
+		TokenType:    g.TokenType,
+		RefreshToken: g.RefreshToken,
+	}
+	switch {
+	case g.ExpiryTime != "":
+		expiry, err := time.Parse(time.RFC3339, g.ExpiryTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expiry_time: %w", err)
+		}
+		t.Expiry = expiry
+	case g.ExpiresIn > 0:
+		t.Expiry = now().Add(time.Duration(g.ExpiresIn) * time.Second)
+	}
+	extra := map[string]interface{}{}
+	if g.IDToken != "" {
+		extra["id_token"] = g.IDToken
+	}
+	if g.Scope != "" {
+		extra["scope"] = g.Scope
+	}
+	return t.WithExtra(extra), nil
+}
+
+// AzureTokenAdapter converts a token in the format of Azure AD (Microsoft identity platform).
+// The expiry is represented as expires_on in Unix time, which may be a string or a number.
+type AzureTokenAdapter struct{}
+
+type azureToken struct {
+	AccessToken  string      `json:"access_token"`
+	TokenType    string      `json:"token_type,omitempty"`
+	RefreshToken string      `json:"refresh_token,omitempty"`
+	IDToken      string      `json:"id_token,omitempty"`
+	Resource     string      `json:"resource,omitempty"`
+	ExpiresIn    json.Number `json:"expires_in,omitempty"`
+	ExpiresOn    json.Number `json:"expires_on,omitempty"`
+}
+
+// ToProviderFormat converts the token to the format of Azure AD.
+func (AzureTokenAdapter) ToProviderFormat(t *oauth2.Token) ([]byte, error) {
+	a := azureToken{
+		AccessToken:  t.AccessToken,
+		TokenType:    t.TokenType,
+		RefreshToken: t.RefreshToken,
+		IDToken:      extraString(t, "id_token"),
+		Resource:     extraString(t, "resource"),
+	}
+	if !t.Expiry.IsZero() {
+		a.ExpiresOn = json.Number(strconv.FormatInt(t.Expiry.Unix(), 10))
+		if expiresIn := int64(t.Expiry.Sub(now()).Seconds()); expiresIn > 0 {
+			a.ExpiresIn = json.Number(strconv.FormatInt(expiresIn, 10))
+		}
+	}
+	return json.Marshal(&a)
+}
+
+// FromProviderFormat converts the token in the format of Azure AD.
+func (AzureTokenAdapter) FromProviderFormat(data []byte) (*oauth2.Token, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	// expires_in and expires_on may be strings
+	a := azureToken{
+		AccessToken:  stringOf(raw["access_token"]),
+		TokenType:    stringOf(raw["token_type"]),
+		RefreshToken: stringOf(raw["refresh_token"]),
+		IDToken:      stringOf(raw["id_token"]),
+		Resource:     stringOf(raw["resource"]),
+		ExpiresIn:    json.Number(stringOf(raw["expires_in"])),
+		ExpiresOn:    json.Number(stringOf(raw["expires_on"])),
+	}
+	t := &oauth2.Token{
+		AccessToken:  a.AccessToken,
+		TokenType:    a.TokenType,
+		RefreshToken: a.RefreshToken,
+	}
+	switch {
+	case a.ExpiresOn != "":
+		expiresOn, err := a.ExpiresOn.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid expires_on: %w", err)
+		}
+		t.Expiry = time.Unix(expiresOn, 0)
+	case a.ExpiresIn != "":
+		expiresIn, err := a.ExpiresIn.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid expires_in: %w", err)
+		}
+		t.Expiry = now().Add(time.Duration(expiresIn) * time.Second)
+	}
+	return t.WithExtra(raw), nil
+}
+
+func stringOf(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// OktaTokenAdapter converts a token in the format of the Okta token storage,
+// which has camel-cased keys and expiresAt in Unix time.
+type OktaTokenAdapter struct{}
+
+type oktaToken struct {
+	AccessToken  string   `json:"accessToken"`
+	TokenType    string   `json:"tokenType,omitempty"`
+	RefreshToken string   `json:"refreshToken,omitempty"`
+	IDToken      string   `json:"idToken,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+	ExpiresAt    int64    `json:"expiresAt,omitempty"`
+}
+
+// ToProviderFormat converts the token to the format of Okta.
+func (OktaTokenAdapter) ToProviderFormat(t *oauth2.Token) ([]byte, error) {
+	o := oktaToken{
+		AccessToken:  t.AccessToken,
+		TokenType:    t.TokenType,
+		RefreshToken: t.RefreshToken,
+		IDToken:      extraString(t, "id_token"),
+	}
+	if scope := extraString(t, "scope"); scope != "" {
+		o.Scopes = strings.Fields(scope)
+	}
+	if !t.Expiry.IsZero() {
+		o.ExpiresAt = t.Expiry.Unix()
+	}
+	return json.Marshal(&o)
+}
+
+// FromProviderFormat converts the token in the format of Okta.
+func (OktaTokenAdapter) FromProviderFormat(data []byte) (*oauth2.Token, error) {
+	var o oktaToken
+	if err := json.Unmarshal(data, &o); err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	t := &oauth2.Token{
+		AccessToken:  o.AccessToken,
+		TokenType:    o.TokenType,
+		RefreshToken: o.RefreshToken,
+	}
+	if o.ExpiresAt > 0 {
+		t.Expiry = time.Unix(o.ExpiresAt, 0)
+	}
+	extra := map[string]interface{}{}
+	if o.IDToken != "" {
+		extra["id_token"] = o.IDToken
+	}
+	if len(o.Scopes) > 0 {
+		extra["scope"] = strings.Join(o.Scopes, " ")
+	}
+	return t.WithExtra(extra), nil
+}
@@ -0,0 +1,70 @@
+package adapters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/oauth2"
+)
+
+func TestTokenAdapters(t *testing.T) {
+	fixedNow := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	defer func(f func() time.Time) { now = f }(now)
+	now = func() time.Time { return fixedNow }
+
+	token := (&oauth2.Token{
+		AccessToken:  "ACCESS_TOKEN",
+		TokenType:    "Bearer",
+		RefreshToken: "REFRESH_TOKEN",
+		Expiry:       fixedNow.Add(time.Hour),
+	}).WithExtra(map[string]interface{}{"id_token": "ID_TOKEN", "scope": "openid email"})
+
+	for name, c := range map[string]struct {
+		adapter TokenAdapter
+		want    string
+	}{
+		"Google": {GoogleTokenAdapter{}, `{"access_token":"ACCESS_TOKEN","token_type":"Bearer","refresh_token":"REFRESH_TOKEN","id_token":"ID_TOKEN","scope":"openid email","expires_in":3600,"expiry_time":"2020-01-02T04:04:05Z"}`},
+		"Azure":  {AzureTokenAdapter{}, `{"access_token":"ACCESS_TOKEN","token_type":"Bearer","refresh_token":"REFRESH_TOKEN","id_token":"ID_TOKEN","expires_in":3600,"expires_on":1577937845}`},
+		"Okta":   {OktaTokenAdapter{}, `{"accessToken":"ACCESS_TOKEN","tokenType":"Bearer","refreshToken":"REFRESH_TOKEN","idToken":"ID_TOKEN","scopes":["openid","email"],"expiresAt":1577937845}`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			b, err := c.adapter.ToProviderFormat(token)
+			if err != nil {
+				t.Fatalf("ToProviderFormat error: %s", err)
+			}
+			if diff := cmp.Diff(c.want, string(b)); diff != "" {
+				t.Errorf("mismatch (-want +got):\n%s", diff)
+			}
+			got, err := c.adapter.FromProviderFormat(b)
+			if err != nil {
+				t.Fatalf("FromProviderFormat error: %s", err)
+			}
+			if got.AccessToken != token.AccessToken || got.RefreshToken != token.RefreshToken || !got.Expiry.Equal(token.Expiry) {
+				t.Errorf("token wants %+v but was %+v", token, got)
+			}
+			if w := "ID_TOKEN"; got.Extra("id_token") != w {
+				t.Errorf("id_token wants %s but was %v", w, got.Extra("id_token"))
+			}
+		})
+	}
+
+	t.Run("GoogleExpiresIn", func(t *testing.T) {
+		got, err := GoogleTokenAdapter{}.FromProviderFormat([]byte(`{"access_token":"ACCESS_TOKEN","expires_in":60}`))
+		if err != nil {
+			t.Fatalf("FromProviderFormat error: %s", err)
+		}
+		if w := fixedNow.Add(time.Minute); !got.Expiry.Equal(w) {
+			t.Errorf("Expiry wants %s but was %s", w, got.Expiry)
+		}
+	})
+	t.Run("AzureExpiresOnString", func(t *testing.T) {
+		got, err := AzureTokenAdapter{}.FromProviderFormat([]byte(`{"access_token":"ACCESS_TOKEN","expires_in":"3599","expires_on":"1577937845"}`))
+		if err != nil {
+			t.Fatalf("FromProviderFormat error: %s", err)
+		}
+		if w := time.Unix(1577937845, 0); !got.Expiry.Equal(w) {
+			t.Errorf("Expiry wants %s but was %s", w, got.Expiry)
+		}
+	})
+}
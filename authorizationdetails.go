@@ -0,0 +1,83 @@
+package oauth2cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"golang.org/x/oauth2"
+)
+
+// AuthorizationDetail represents an element of authorization_details of RFC 9396.
+// See https://www.rfc-editor.org/rfc/rfc9396
+type AuthorizationDetail struct {
+	// Type of the authorization details, e.g. payment_initiation.
+	Type string
+	// Other fields depending on the type, e.g. locations, actions or identifier.
+	Extra map[string]interface{}
+}
+
+// MarshalJSON encodes the detail to a JSON object with type and the extra fields.
+func (d AuthorizationDetail) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(d.Extra)+1)
+	for k, v := range d.Extra {
+		m[k] = v
+	}
+	m["type"] = d.Type
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON decodes a JSON object to the detail.
+func (d *AuthorizationDetail) UnmarshalJSON(b []byte) error {
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	typ, ok := m["type"].(string)
+	if !ok {
+		return fmt.Errorf("type must be a string but was %v", m["type"])
+	}
+	delete(m, "type")
+	d.Type = typ
+	d.Extra = nil
+	if len(m) > 0 {
+		d.Extra = m
+	}
+	return nil
+}
+
+// authorizationDetailsOption returns the option of authorization_details in the authorization request.
+func authorizationDetailsOption(details []AuthorizationDetail) (oauth2.AuthCodeOption, error) {
+	b, err := json.Marshal(details)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode authorization_details: %w", err)
+	}
+	return oauth2.SetAuthURLParam("authorization_details", string(b)), nil
+}
+
+// ExtractAuthorizationDetails returns authorization_details in the token response.
+// It returns nil if the token response does not have it.
+func ExtractAuthorizationDetails(token *oauth2.Token) ([]AuthorizationDetail, error) {
+	if token == nil {
+		return nil, nil
+	}
+	v := token.Extra("authorization_details")
+	if v == nil {
+		return nil, nil
+	}
+	var b []byte
+	switch v := v.(type) {
+	case string:
+		// a form-encoded token response has the raw JSON
+		b = []byte(v)
+	default:
+		var err error
+		if b, err = json.Marshal(v); err != nil {
+			return nil, fmt.Errorf("invalid authorization_details: %w", err)
+		}
+	}
+	var details []AuthorizationDetail
+	if err := json.Unmarshal(b, &details); err != nil {
+		return nil, fmt.Errorf("invalid authorization_details: %w", err)
+	}
+	return details, nil
+}
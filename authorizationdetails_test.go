@@ -0,0 +1,69 @@
+package oauth2cli
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestConfig_AuthorizationDetails(t *testing.T) {
+	cfg := Config{
+		OAuth2Config: oauth2.Config{Endpoint: oauth2.Endpoint{AuthURL: "https://example.com/auth"}},
+		AuthorizationDetails: []AuthorizationDetail{
+			{Type: "payment_initiation", Extra: map[string]interface{}{"actions": []string{"initiate"}}},
+		},
+	}
+	if err := cfg.validateAndSetDefaults(); err != nil {
+		t.Fatalf("validateAndSetDefaults error: %s", err)
+	}
+	u, err := url.Parse(cfg.OAuth2Config.AuthCodeURL("STATE", cfg.AuthCodeOptions...))
+	if err != nil {
+		t.Fatalf("invalid URL: %s", err)
+	}
+	want := `[{"actions":["initiate"],"type":"payment_initiation"}]`
+	if got := u.Query().Get("authorization_details"); got != want {
+		t.Errorf("authorization_details wants %s but was %s", want, got)
+	}
+}
+
+func TestExtractAuthorizationDetails(t *testing.T) {
+	want := []AuthorizationDetail{
+		{Type: "payment_initiation", Extra: map[string]interface{}{"identifier": "ID"}},
+		{Type: "account_information"},
+	}
+	t.Run("JSON", func(t *testing.T) {
+		token := (&oauth2.Token{AccessToken: "ACCESS_TOKEN"}).WithExtra(map[string]interface{}{
+			"authorization_details": []interface{}{
+				map[string]interface{}{"type": "payment_initiation", "identifier": "ID"},
+				map[string]interface{}{"type": "account_information"},
+			},
+		})
+		got, err := ExtractAuthorizationDetails(token)
+		if err != nil {
+			t.Fatalf("ExtractAuthorizationDetails error: %s", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("wants %+v but was %+v", want, got)
+		}
+	})
+	t.Run("FormEncoded", func(t *testing.T) {
+		token := (&oauth2.Token{AccessToken: "ACCESS_TOKEN"}).WithExtra(url.Values{
+			"authorization_details": {`[{"type":"payment_initiation","identifier":"ID"},{"type":"account_information"}]`},
+		})
+		got, err := ExtractAuthorizationDetails(token)
+		if err != nil {
+			t.Fatalf("ExtractAuthorizationDetails error: %s", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("wants %+v but was %+v", want, got)
+		}
+	})
+	t.Run("None", func(t *testing.T) {
+		got, err := ExtractAuthorizationDetails(&oauth2.Token{AccessToken: "ACCESS_TOKEN"})
+		if err != nil || got != nil {
+			t.Errorf("wants nil but was %+v, %v", got, err)
+		}
+	})
+}
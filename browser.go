@@ -0,0 +1,12 @@
+package oauth2cli
+
+// BrowserOpener opens a URL in a browser.
+type BrowserOpener interface {
+	OpenURL(url string) error
+}
+
+// BrowserOpenerFunc is a function which implements BrowserOpener.
+type BrowserOpenerFunc func(url string) error
+
+// OpenURL calls the function.
+func (f BrowserOpenerFunc) OpenURL(url string) error { return f(url) }
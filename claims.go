@@ -0,0 +1,93 @@
+package oauth2cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/int128/oauth2cli/internal/jose"
+	"golang.org/x/oauth2"
+)
+
+// TokenClaimsKey is the context key of the claims set by GetTokenWithContext.
+type TokenClaimsKey struct{}
+
+// IDTokenClaims represents the claims of an ID token or a JWT access token.
+// Note that the signature is not verified.
+type IDTokenClaims struct {
+	Issuer        string
+	Subject       string
+	Audience      []string
+	Expiry        time.Time
+	IssuedAt      time.Time
+	Email         string
+	EmailVerified bool
+	Name          string
+	// All claims in the token.
+	Claims map[string]interface{}
+}
+
+// GetTokenWithContext performs the same flow as GetToken.
+// It returns a context with the claims of the token,
+// which is available by TokenClaimsFromContext.
+//
+// The claims are taken from the ID token, or the access token if it is a JWT.
+// If neither is a JWT, the context does not contain the claims.
+func GetTokenWithContext(ctx context.Context, config Config) (context.Context, *oauth2.Token, error) {
+	token, err := GetToken(ctx, config)
+	if err != nil {
+		return ctx, nil, err
+	}
+	claims, err := extractTokenClaims(token)
+	if err != nil {
+		return ctx, token, nil
+	}
+	return context.WithValue(ctx, TokenClaimsKey{}, claims), token, nil
+}
+
+// TokenClaimsFromContext returns the claims set by GetTokenWithContext.
+func TokenClaimsFromContext(ctx context.Context) (*IDTokenClaims, bool) {
+	claims, ok := ctx.Value(TokenClaimsKey{}).(*IDTokenClaims)
+	return claims, ok
+}
+
+func extractTokenClaims(token *oauth2.Token) (*IDTokenClaims, error) {
+	if idToken, ok := token.Extra("id_token").(string); ok && idToken != "" {
+		jwt, err := jose.Decode(idToken)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id_token: %w", err)
+		}
+		return newIDTokenClaims(jwt.Claims), nil
+	}
+	jwt, err := jose.Decode(token.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("access token is not a JWT: %w", err)
+	}
+	return newIDTokenClaims(jwt.Claims), nil
+}
+
+func newIDTokenClaims(m map[string]interface{}) *IDTokenClaims {
+	c := &IDTokenClaims{Claims: m}
+	c.Issuer, _ = m["iss"].(string)
+	c.Subject, _ = m["sub"].(string)
+	c.Email, _ = m["email"].(string)
+	c.EmailVerified, _ = m["email_verified"].(bool)
+	c.Name, _ = m["name"].(string)
+	switch aud := m["aud"].(type) {
+	case string:
+		c.Audience = []string{aud}
+	case []interface{}:
+		for _, v := range aud {
+			if s, ok := v.(string); ok {
+				c.Audience = append(c.Audience, s)
+			}
+		}
+	}
+	if exp, ok := m["exp"].(float64); ok {
+		c.Expiry = time.Unix(int64(exp), 0)
+	}
+	if iat, ok := m["iat"].(float64); ok {
+		c.IssuedAt = time.Unix(int64(iat), 0)
+	}
+	return c
+}
@@ -0,0 +1,68 @@
+package oauth2cli
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/oauth2"
+)
+
+func Test_extractTokenClaims(t *testing.T) {
+	t.Run("IDToken", func(t *testing.T) {
+		idToken := newTestJWT(`{"alg":"RS256"}`, `{"iss":"https://issuer.example.com","sub":"SUBJECT","aud":["CLIENT_ID","API"],"exp":1600000000,"iat":1500000000,"email":"alice@example.com","email_verified":true,"name":"Alice"}`)
+		token := (&oauth2.Token{AccessToken: "ACCESS_TOKEN"}).WithExtra(map[string]interface{}{"id_token": idToken})
+		claims, err := extractTokenClaims(token)
+		if err != nil {
+			t.Fatalf("extractTokenClaims error: %s", err)
+		}
+		claims.Claims = nil
+		want := &IDTokenClaims{
+			Issuer:        "https://issuer.example.com",
+			Subject:       "SUBJECT",
+			Audience:      []string{"CLIENT_ID", "API"},
+			Expiry:        time.Unix(1600000000, 0),
+			IssuedAt:      time.Unix(1500000000, 0),
+			Email:         "alice@example.com",
+			EmailVerified: true,
+			Name:          "Alice",
+		}
+		if diff := cmp.Diff(want, claims); diff != "" {
+			t.Errorf("claims mismatch (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("AccessToken", func(t *testing.T) {
+		token := &oauth2.Token{AccessToken: newTestJWT(`{"alg":"RS256"}`, `{"sub":"SUBJECT","aud":"API"}`)}
+		claims, err := extractTokenClaims(token)
+		if err != nil {
+			t.Fatalf("extractTokenClaims error: %s", err)
+		}
+		if claims.Subject != "SUBJECT" {
+			t.Errorf("Subject wants SUBJECT but was %s", claims.Subject)
+		}
+		if diff := cmp.Diff([]string{"API"}, claims.Audience); diff != "" {
+			t.Errorf("Audience mismatch (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("OpaqueToken", func(t *testing.T) {
+		token := &oauth2.Token{AccessToken: "ACCESS_TOKEN"}
+		if _, err := extractTokenClaims(token); err == nil {
+			t.Errorf("extractTokenClaims wants error but was nil")
+		}
+	})
+}
+
+func TestTokenClaimsFromContext(t *testing.T) {
+	if _, ok := TokenClaimsFromContext(context.TODO()); ok {
+		t.Errorf("TokenClaimsFromContext wants false but was true")
+	}
+	ctx := context.WithValue(context.TODO(), TokenClaimsKey{}, &IDTokenClaims{Subject: "SUBJECT"})
+	claims, ok := TokenClaimsFromContext(ctx)
+	if !ok {
+		t.Fatalf("TokenClaimsFromContext wants true but was false")
+	}
+	if claims.Subject != "SUBJECT" {
+		t.Errorf("Subject wants SUBJECT but was %s", claims.Subject)
+	}
+}
@@ -0,0 +1,69 @@
+package oauth2cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const clipboardTimeout = 3 * time.Second
+
+// clipboardCommands returns the candidates of a command which writes the stdin to the clipboard.
+var clipboardCommands = func() [][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"clip"}}
+	}
+	return [][]string{
+		{"wl-copy"},
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+	}
+}
+
+var clipboardOutput io.Writer = os.Stderr
+
+// copyAuthURLToClipboard copies the URL to the clipboard and shows a message.
+// If the clipboard is not available, e.g. a headless server, this shows the URL instead.
+func copyAuthURLToClipboard(u string) {
+	if err := copyToClipboard(u); err != nil {
+		_, _ = fmt.Fprintf(clipboardOutput, "Open %s in your browser\n", u)
+		return
+	}
+	_, _ = fmt.Fprintln(clipboardOutput, "Authorization URL copied to clipboard. Paste it in your browser.")
+}
+
+func copyToClipboard(s string) error {
+	var errs []string
+	for _, args := range clipboardCommands() {
+		name, err := exec.LookPath(args[0])
+		if err != nil {
+			continue
+		}
+		if err := runClipboardCommand(name, args[1:], s); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", args[0], err))
+			continue
+		}
+		return nil
+	}
+	if len(errs) == 0 {
+		return errors.New("no clipboard command found")
+	}
+	return fmt.Errorf("could not copy to the clipboard: %s", strings.Join(errs, ", "))
+}
+
+func runClipboardCommand(name string, args []string, s string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), clipboardTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = strings.NewReader(s)
+	return cmd.Run()
+}
@@ -0,0 +1,115 @@
+package oauth2cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestCopyAuthURLToClipboard(t *testing.T) {
+	defer func(commands func() [][]string) { clipboardCommands = commands }(clipboardCommands)
+	defer func() { clipboardOutput = os.Stderr }()
+
+	t.Run("Copied", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "oauth2cli")
+		if err != nil {
+			t.Fatalf("could not create a temp dir: %s", err)
+		}
+		defer os.RemoveAll(dir)
+		clipboard := filepath.Join(dir, "clipboard")
+		clipboardCommands = func() [][]string {
+			return [][]string{{"no-such-clipboard-command"}, {"sh", "-c", "cat > " + clipboard}}
+		}
+		var out bytes.Buffer
+		clipboardOutput = &out
+
+		copyAuthURLToClipboard("http://localhost:8000")
+		b, err := ioutil.ReadFile(clipboard)
+		if err != nil {
+			t.Fatalf("could not read the clipboard: %s", err)
+		}
+		if w := "http://localhost:8000"; string(b) != w {
+			t.Errorf("clipboard wants %s but was %s", w, b)
+		}
+		if w := "Authorization URL copied to clipboard. Paste it in your browser.\n"; out.String() != w {
+			t.Errorf("output wants %q but was %q", w, out.String())
+		}
+	})
+
+	t.Run("Fallback", func(t *testing.T) {
+		clipboardCommands = func() [][]string {
+			return [][]string{{"no-such-clipboard-command"}}
+		}
+		var out bytes.Buffer
+		clipboardOutput = &out
+
+		copyAuthURLToClipboard("http://localhost:8000")
+		if w := "Open http://localhost:8000 in your browser\n"; out.String() != w {
+			t.Errorf("output wants %q but was %q", w, out.String())
+		}
+	})
+}
+
+func TestReceiveCodeViaLocalServer_CopyAuthURLToClipboard(t *testing.T) {
+	defer func(commands func() [][]string) { clipboardCommands = commands }(clipboardCommands)
+	defer func() { clipboardOutput = os.Stderr }()
+	dir, err := ioutil.TempDir("", "oauth2cli")
+	if err != nil {
+		t.Fatalf("could not create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	clipboard := filepath.Join(dir, "clipboard")
+	clipboardCommands = func() [][]string {
+		return [][]string{{"sh", "-c", "cat > " + clipboard}}
+	}
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	clipboardOutput = pw
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+	defer cancel()
+	readyCh := make(chan string, 1)
+	cfg := Config{
+		State:                  "STATE",
+		OAuth2Config:           oauth2.Config{Endpoint: oauth2.Endpoint{AuthURL: "https://example.com/auth"}},
+		CopyAuthURLToClipboard: true,
+		LocalServerReadyChan:   readyCh,
+	}
+	if err := cfg.validateAndSetDefaults(); err != nil {
+		t.Fatalf("validateAndSetDefaults error: %s", err)
+	}
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	go func() {
+		u := <-readyCh
+		// wait for the copy, which does not block the flow
+		if _, err := bufio.NewReader(pr).ReadString('\n'); err != nil {
+			t.Errorf("could not read the output: %s", err)
+		}
+		resp, err := client.Get(u + "/?state=STATE&code=AUTH_CODE")
+		if err != nil {
+			t.Errorf("could not send a request: %s", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+	if _, err := receiveCodeViaLocalServer(ctx, &cfg); err != nil {
+		t.Fatalf("receiveCodeViaLocalServer error: %s", err)
+	}
+	b, err := ioutil.ReadFile(clipboard)
+	if err != nil {
+		t.Fatalf("could not read the clipboard: %s", err)
+	}
+	if !strings.HasPrefix(string(b), "https://example.com/auth?") || !strings.Contains(string(b), "state=STATE") {
+		t.Errorf("clipboard wants the authorization URL but was %s", b)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Output formats.
+const (
+	formatJSON   = "json"
+	formatEnv    = "env"
+	formatBearer = "bearer"
+)
+
+func isValidFormat(format string) bool {
+	switch format {
+	case formatJSON, formatEnv, formatBearer:
+		return true
+	}
+	return false
+}
+
+type tokenJSON struct {
+	AccessToken  string     `json:"access_token"`
+	TokenType    string     `json:"token_type,omitempty"`
+	RefreshToken string     `json:"refresh_token,omitempty"`
+	IDToken      string     `json:"id_token,omitempty"`
+	Expiry       *time.Time `json:"expiry,omitempty"`
+}
+
+func newTokenJSON(token *oauth2.Token) tokenJSON {
+	t := tokenJSON{
+		AccessToken:  token.AccessToken,
+		TokenType:    token.TokenType,
+		RefreshToken: token.RefreshToken,
+	}
+	t.IDToken, _ = token.Extra("id_token").(string)
+	if !token.Expiry.IsZero() {
+		expiry := token.Expiry.UTC()
+		t.Expiry = &expiry
+	}
+	return t
+}
+
+func writeToken(w io.Writer, format string, token *oauth2.Token) error {
+	switch format {
+	case formatJSON:
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		if err := e.Encode(newTokenJSON(token)); err != nil {
+			return fmt.Errorf("could not encode the token: %w", err)
+		}
+		return nil
+	case formatEnv:
+		t := newTokenJSON(token)
+		vars := [][2]string{
+			{"OAUTH2_ACCESS_TOKEN", t.AccessToken},
+			{"OAUTH2_TOKEN_TYPE", t.TokenType},
+			{"OAUTH2_REFRESH_TOKEN", t.RefreshToken},
+			{"OAUTH2_ID_TOKEN", t.IDToken},
+		}
+		if t.Expiry != nil {
+			vars = append(vars, [2]string{"OAUTH2_EXPIRY", t.Expiry.Format(time.RFC3339)})
+		}
+		for _, v := range vars {
+			if v[1] == "" {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "export %s=%s\n", v[0], shellQuote(v[1])); err != nil {
+				return fmt.Errorf("write error: %w", err)
+			}
+		}
+		return nil
+	case formatBearer:
+		if _, err := fmt.Fprintln(w, token.AccessToken); err != nil {
+			return fmt.Errorf("write error: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// shellQuote returns the string quoted for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
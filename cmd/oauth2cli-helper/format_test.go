@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/oauth2"
+)
+
+func Test_writeToken(t *testing.T) {
+	token := (&oauth2.Token{
+		AccessToken:  "ACCESS_TOKEN",
+		TokenType:    "Bearer",
+		RefreshToken: "REFRESH'TOKEN",
+		Expiry:       time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+	}).WithExtra(map[string]interface{}{"id_token": "ID_TOKEN"})
+
+	for _, c := range []struct {
+		format string
+		want   string
+	}{
+		{formatJSON, `{
+  "access_token": "ACCESS_TOKEN",
+  "token_type": "Bearer",
+  "refresh_token": "REFRESH'TOKEN",
+  "id_token": "ID_TOKEN",
+  "expiry": "2020-01-02T03:04:05Z"
+}
+`},
+		{formatEnv, `export OAUTH2_ACCESS_TOKEN='ACCESS_TOKEN'
+export OAUTH2_TOKEN_TYPE='Bearer'
+export OAUTH2_REFRESH_TOKEN='REFRESH'\''TOKEN'
+export OAUTH2_ID_TOKEN='ID_TOKEN'
+export OAUTH2_EXPIRY='2020-01-02T03:04:05Z'
+`},
+		{formatBearer, "ACCESS_TOKEN\n"},
+	} {
+		t.Run(c.format, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := writeToken(&b, c.format, token); err != nil {
+				t.Fatalf("writeToken error: %s", err)
+			}
+			if diff := cmp.Diff(c.want, b.String()); diff != "" {
+				t.Errorf("output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("UnknownFormat", func(t *testing.T) {
+		var b bytes.Buffer
+		if err := writeToken(&b, "yaml", token); err == nil {
+			t.Errorf("writeToken wants error but was nil")
+		}
+	})
+}
+
+func Test_setFlagsFromEnv(t *testing.T) {
+	f := flag.NewFlagSet("test", flag.ContinueOnError)
+	clientID := f.String("client-id", "", "")
+	pkce := f.Bool("pkce", true, "")
+	env := map[string]string{
+		"OAUTH2CLI_CLIENT_ID": "CLIENT_ID",
+		"OAUTH2CLI_PKCE":      "false",
+	}
+	lookupEnv := func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}
+	if err := setFlagsFromEnv(f, lookupEnv); err != nil {
+		t.Fatalf("setFlagsFromEnv error: %s", err)
+	}
+	if *clientID != "CLIENT_ID" {
+		t.Errorf("client-id wants CLIENT_ID but was %s", *clientID)
+	}
+	if *pkce {
+		t.Errorf("pkce wants false but was true")
+	}
+	if err := f.Parse([]string{"--client-id", "OVERRIDE"}); err != nil {
+		t.Fatalf("Parse error: %s", err)
+	}
+	if *clientID != "OVERRIDE" {
+		t.Errorf("client-id wants OVERRIDE but was %s", *clientID)
+	}
+
+	env["OAUTH2CLI_PKCE"] = "INVALID"
+	if err := setFlagsFromEnv(f, lookupEnv); err == nil {
+		t.Errorf("setFlagsFromEnv wants error but was nil")
+	}
+}
@@ -0,0 +1,317 @@
+// Command oauth2cli-helper gets a token by the Authorization Code Grant Flow
+// and writes it to stdout, so that a shell script can access an API.
+//
+// Each flag can be set by the environment variable of OAUTH2CLI_ and its name,
+// e.g. OAUTH2CLI_CLIENT_SECRET for --client-secret.
+// A flag overrides the environment variable.
+//
+// The flags cover the scalar fields of oauth2cli.Config.
+// See unsupportedFields for the fields which cannot be set by a flag.
+//
+//	export OAUTH2CLI_CLIENT_ID=xxx OAUTH2CLI_CLIENT_SECRET=xxx
+//	eval "$(oauth2cli-helper --auth-url https://... --token-url https://... --format env)"
+//	curl -H "Authorization: Bearer $OAUTH2_ACCESS_TOKEN" https://...
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/int128/oauth2cli"
+	"github.com/int128/oauth2cli/oauth2params"
+	"github.com/pkg/browser"
+	"golang.org/x/oauth2"
+	"golang.org/x/sync/errgroup"
+)
+
+const envPrefix = "OAUTH2CLI_"
+
+// unsupportedFields are the fields of oauth2cli.Config which cannot be set by a flag,
+// because they are not scalar or need the code.
+const unsupportedFields = `AuthCodeOptions (except --pkce and --access-type-offline), TokenRequestOptions,
+AuthorizationDetails, EnableNetTrace, StatsChan, StatsInterval, OnTokenRefreshed, OnTokenRefreshFailed, TokenExpirySlack,
+SessionStateValidator, SessionDeduplicator, InterruptionRecoveryCache, InterruptionRecoveryTTL,
+ExchangeCodeHook, PreflightOIDCCheck, AuthorizationURLValidator, LocalServerClientCertValidator,
+TunnelProvider, LocalServerMiddleware, LocalServerRedirectMiddleware, LocalServerReadyChan,
+LocalServerOnStarted, OAuth2ConfigMutator, BrowserOpener (see --open-browser),
+LocalServerAddress and LocalServerPort (deprecated)`
+
+type cmdOptions struct {
+	authURL                string
+	tokenURL               string
+	clientID               string
+	clientSecret           string
+	scopes                 string
+	additionalScopes       string
+	responseType           string
+	skipResponseTypeCheck  bool
+	pkceRegistration       bool
+	pkceRegisteredMethods  string
+	authorizationTimeout   time.Duration
+	handleOSSignals        bool
+	tokenEndpointTimeout   time.Duration
+	tokenEndpointKeepAlive time.Duration
+	tokenResponseJWT       bool
+	tokenResponseJWKSURL   string
+	tokenResponseJWTIssuer string
+	debug                  bool
+	verboseHeaders         bool
+	state                  string
+	hybridResponseType     string
+	redirectURLHostname    string
+	bindAddress            string
+	fifoPath               string
+	localServerCert        string
+	localServerKey         string
+	localServerScheme      string
+	tcpKeepAlive           time.Duration
+	acceptTimeout          time.Duration
+	requestTimeout         time.Duration
+	randomizePath          bool
+	localServerSuccessHTML string
+	localServerErrorHTML   string
+	alreadyUsedHTML        string
+	timeoutHTML            string
+	suppressWindowClose    bool
+	singleUse              *bool
+	allowDuplicateState    bool
+	corsOrigins            string
+	corsAllowCredentials   bool
+	responseBodyLimit      int64
+	earlyClose             bool
+	proxyProto             bool
+	stateLength            int
+	stateMaxLength         int
+	validateCHash          bool
+	pkce                   bool
+	accessTypeOffline      bool
+	copyAuthURLToClipboard bool
+	openBrowser            bool
+	timeout                time.Duration
+	format                 string
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("oauth2cli-helper: ")
+
+	var o cmdOptions
+	f := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	f.StringVar(&o.authURL, "auth-url", "", "Authorization URL of the provider")
+	f.StringVar(&o.tokenURL, "token-url", "", "Token URL of the provider")
+	f.StringVar(&o.clientID, "client-id", "", "OAuth client ID")
+	f.StringVar(&o.clientSecret, "client-secret", "", "OAuth client secret (optional)")
+	f.StringVar(&o.scopes, "scopes", "", "Scopes to request, comma separated")
+	f.StringVar(&o.additionalScopes, "additional-scopes", "", "Scopes to request in addition to --scopes, comma separated")
+	f.StringVar(&o.responseType, "response-type", "", "response_type of the authorization request (default code)")
+	f.BoolVar(&o.skipResponseTypeCheck, "skip-response-type-validation", false, "Allow a response_type other than code, code id_token and code token")
+	f.BoolVar(&o.pkceRegistration, "pkce-registration", false, "Verify the PKCE method against --pkce-registered-methods")
+	f.StringVar(&o.pkceRegisteredMethods, "pkce-registered-methods", "", "PKCE methods registered for the client, comma separated")
+	f.DurationVar(&o.authorizationTimeout, "authorization-timeout", 0, "Timeout until the authorization response, shown in the browser (optional)")
+	f.BoolVar(&o.handleOSSignals, "handle-os-signals", false, "Cancel the authorization on SIGINT or SIGTERM")
+	f.DurationVar(&o.tokenEndpointTimeout, "token-endpoint-timeout", 0, "Timeout of the token request (optional)")
+	f.DurationVar(&o.tokenEndpointKeepAlive, "token-endpoint-keep-alive", 0, "TCP keep-alive period of the token request, negative to disable (optional)")
+	f.BoolVar(&o.tokenResponseJWT, "token-response-jwt", false, "Verify the token response in JWT")
+	f.StringVar(&o.tokenResponseJWKSURL, "token-response-jwks-url", "", "JWKS URL to verify the token response in JWT")
+	f.StringVar(&o.tokenResponseJWTIssuer, "token-response-jwt-issuer", "", "Issuer of the token response in JWT")
+	f.BoolVar(&o.debug, "debug", false, "Write the debug logs to stderr")
+	f.BoolVar(&o.verboseHeaders, "local-server-verbose-headers", false, "Write the headers of each request to the debug logs")
+	f.StringVar(&o.state, "state", "", "State parameter (default random)")
+	f.StringVar(&o.hybridResponseType, "hybrid-response-type", "", "response_type of the hybrid flow, e.g. code id_token (optional)")
+	f.StringVar(&o.redirectURLHostname, "redirect-url-hostname", "", "Hostname of the redirect URL (default localhost)")
+	f.StringVar(&o.bindAddress, "bind-address", "", "Addresses which the local server binds to, comma separated (default 127.0.0.1:0)")
+	f.StringVar(&o.fifoPath, "local-server-fifo", "", "Path to a named pipe to read the redirect URL from, instead of the local server (optional)")
+	f.StringVar(&o.localServerCert, "local-server-cert", "", "Path to a certificate file for the local server (optional)")
+	f.StringVar(&o.localServerKey, "local-server-key", "", "Path to a key file for the local server (optional)")
+	f.StringVar(&o.localServerScheme, "local-server-scheme", "", "Scheme of the redirect URL, http or https (optional)")
+	f.DurationVar(&o.tcpKeepAlive, "local-server-tcp-keep-alive", 0, "TCP keep-alive period of the local server (optional)")
+	f.DurationVar(&o.acceptTimeout, "local-server-accept-timeout", 0, "Interval to check the context while waiting for a connection to the local server (optional)")
+	f.DurationVar(&o.requestTimeout, "local-server-request-timeout", 0, "Timeout of each request to the local server (optional)")
+	f.BoolVar(&o.randomizePath, "local-server-randomize-path", false, "Add a random path to the redirect URL")
+	f.StringVar(&o.localServerSuccessHTML, "local-server-success-html", "", "Response HTML body on authorization completed (optional)")
+	f.StringVar(&o.localServerErrorHTML, "local-server-error-html", "", "Template of the response HTML body on authorization error (optional)")
+	f.StringVar(&o.alreadyUsedHTML, "local-server-already-used-html", "", "Response HTML body on a request after the authorization (optional)")
+	f.StringVar(&o.timeoutHTML, "local-server-timeout-html", "", "Template of the HTML body with the remaining time of --authorization-timeout (optional)")
+	f.BoolVar(&o.suppressWindowClose, "suppress-window-close", false, "Do not close the browser tab after authorization")
+	f.BoolFunc("local-server-single-use", "Reject the requests after the authorization (default true)", func(s string) error {
+		v, err := strconv.ParseBool(s)
+		o.singleUse = &v
+		return err
+	})
+	f.BoolVar(&o.allowDuplicateState, "allow-duplicate-state", false, "Allow the redirect with the same state after the first one")
+	f.StringVar(&o.corsOrigins, "local-server-cors-origins", "", "Origins allowed to access the local server by CORS, comma separated (optional)")
+	f.BoolVar(&o.corsAllowCredentials, "local-server-cors-allow-credentials", false, "Allow the credentials in CORS requests")
+	f.Int64Var(&o.responseBodyLimit, "local-server-response-body-limit", 0, "Maximum size of a request body to the local server in bytes (default 64 KB)")
+	f.BoolVar(&o.earlyClose, "local-server-early-close", false, "Close the connection without waiting for the response page")
+	f.BoolVar(&o.proxyProto, "proxy-proto", false, "Read the PROXY protocol header of each connection")
+	f.IntVar(&o.stateLength, "state-length", 0, "Number of random bytes of the state (default 32)")
+	f.IntVar(&o.stateMaxLength, "state-max-length", 0, "Maximum length of the state (default unlimited)")
+	f.BoolVar(&o.validateCHash, "validate-c-hash", false, "Verify the c_hash claim of the ID token in the authorization response")
+	f.BoolVar(&o.pkce, "pkce", true, "Use PKCE")
+	f.BoolVar(&o.accessTypeOffline, "access-type-offline", false, "Set access_type=offline to the authorization request")
+	f.BoolVar(&o.copyAuthURLToClipboard, "copy-url-to-clipboard", false, "Copy the URL to the clipboard instead of opening the browser")
+	f.BoolVar(&o.openBrowser, "open-browser", true, "Open the browser")
+	f.DurationVar(&o.timeout, "timeout", 5*time.Minute, "Timeout of the authorization")
+	f.StringVar(&o.format, "format", formatJSON, "Output format: json, env or bearer")
+	f.Usage = func() {
+		_, _ = fmt.Fprintf(f.Output(), "Usage of %s:\n", f.Name())
+		f.PrintDefaults()
+		_, _ = fmt.Fprintf(f.Output(), "\nThe following fields of oauth2cli.Config are not supported:\n%s\n", unsupportedFields)
+	}
+	if err := setFlagsFromEnv(f, os.LookupEnv); err != nil {
+		log.Fatalf("invalid environment variable: %s", err)
+	}
+	_ = f.Parse(os.Args[1:])
+
+	if err := run(o); err != nil {
+		log.Fatalf("error: %s", err)
+	}
+}
+
+// setFlagsFromEnv sets the flags from the environment variables.
+// This must be called before parsing the arguments.
+func setFlagsFromEnv(f *flag.FlagSet, lookupEnv func(string) (string, bool)) error {
+	var err error
+	f.VisitAll(func(fl *flag.Flag) {
+		if err != nil {
+			return
+		}
+		name := envPrefix + strings.ToUpper(strings.ReplaceAll(fl.Name, "-", "_"))
+		v, ok := lookupEnv(name)
+		if !ok {
+			return
+		}
+		if e := fl.Value.Set(v); e != nil {
+			err = fmt.Errorf("%s: %w", name, e)
+		}
+	})
+	return err
+}
+
+func run(o cmdOptions) error {
+	if o.authURL == "" || o.tokenURL == "" || o.clientID == "" {
+		return fmt.Errorf("you need to set --auth-url, --token-url and --client-id")
+	}
+	if !isValidFormat(o.format) {
+		return fmt.Errorf("unknown format %q", o.format)
+	}
+	ready := make(chan string, 1)
+	cfg := oauth2cli.Config{
+		OAuth2Config: oauth2.Config{
+			ClientID:     o.clientID,
+			ClientSecret: o.clientSecret,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  o.authURL,
+				TokenURL: o.tokenURL,
+			},
+			Scopes: splitList(o.scopes),
+		},
+		AdditionalScopes:                splitList(o.additionalScopes),
+		ResponseType:                    o.responseType,
+		SkipResponseTypeValidation:      o.skipResponseTypeCheck,
+		PKCERegistrationEnabled:         o.pkceRegistration,
+		PKCERegisteredMethods:           splitList(o.pkceRegisteredMethods),
+		AuthorizationTimeout:            o.authorizationTimeout,
+		HandleOSSignals:                 o.handleOSSignals,
+		TokenEndpointTimeout:            o.tokenEndpointTimeout,
+		TokenEndpointKeepAlive:          o.tokenEndpointKeepAlive,
+		TokenResponseJWT:                o.tokenResponseJWT,
+		TokenResponseJWKSURL:            o.tokenResponseJWKSURL,
+		TokenResponseJWTIssuer:          o.tokenResponseJWTIssuer,
+		LocalServerVerboseHeaders:       o.verboseHeaders,
+		State:                           o.state,
+		HybridResponseType:              o.hybridResponseType,
+		RedirectURLHostname:             o.redirectURLHostname,
+		LocalServerBindAddress:          splitList(o.bindAddress),
+		LocalServerFIFOPath:             o.fifoPath,
+		LocalServerCertFile:             o.localServerCert,
+		LocalServerKeyFile:              o.localServerKey,
+		LocalServerScheme:               o.localServerScheme,
+		LocalServerTCPKeepAlive:         o.tcpKeepAlive,
+		LocalServerAcceptTimeout:        o.acceptTimeout,
+		LocalServerRequestTimeout:       o.requestTimeout,
+		LocalServerRandomizePath:        o.randomizePath,
+		LocalServerSuccessHTML:          o.localServerSuccessHTML,
+		LocalServerErrorHTML:            o.localServerErrorHTML,
+		LocalServerAlreadyUsedHTML:      o.alreadyUsedHTML,
+		LocalServerTimeoutHTML:          o.timeoutHTML,
+		LocalServerSuppressWindowClose:  o.suppressWindowClose,
+		LocalServerSingleUse:            o.singleUse,
+		AllowDuplicateState:             o.allowDuplicateState,
+		LocalServerCORSOrigins:          splitList(o.corsOrigins),
+		LocalServerCORSAllowCredentials: o.corsAllowCredentials,
+		LocalServerResponseBodyLimit:    o.responseBodyLimit,
+		LocalServerEarlyClose:           o.earlyClose,
+		LocalServerProxyProto:           o.proxyProto,
+		StateLength:                     o.stateLength,
+		StateMaxLength:                  o.stateMaxLength,
+		ValidateCHash:                   o.validateCHash,
+		CopyAuthURLToClipboard:          o.copyAuthURLToClipboard,
+		LocalServerReadyChan:            ready,
+	}
+	if o.debug {
+		cfg.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+	if o.pkce {
+		pkce, err := oauth2params.NewPKCE()
+		if err != nil {
+			return fmt.Errorf("could not generate PKCE parameters: %w", err)
+		}
+		cfg.AuthCodeOptions = append(cfg.AuthCodeOptions, pkce.AuthCodeOptions()...)
+		cfg.TokenRequestOptions = append(cfg.TokenRequestOptions, pkce.TokenRequestOptions()...)
+	}
+	if o.accessTypeOffline {
+		cfg.AuthCodeOptions = append(cfg.AuthCodeOptions, oauth2.AccessTypeOffline)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+	defer cancel()
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.Go(func() error {
+		select {
+		case url := <-ready:
+			if o.copyAuthURLToClipboard {
+				return nil
+			}
+			log.Printf("Open %s", url)
+			if o.openBrowser {
+				if err := browser.OpenURL(url); err != nil {
+					log.Printf("could not open the browser: %s", err)
+				}
+			}
+			return nil
+		case <-ctx.Done():
+			return fmt.Errorf("context done while waiting for authorization: %w", ctx.Err())
+		}
+	})
+	var token *oauth2.Token
+	eg.Go(func() error {
+		var err error
+		token, err = oauth2cli.GetToken(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("could not get a token: %w", err)
+		}
+		return nil
+	})
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+	return writeToken(os.Stdout, o.format, token)
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,136 @@
+package oauth2cli
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const defaultInterruptionRecoveryTTL = 1 * time.Minute
+
+// CodeCache stores authorization codes which have not been exchanged yet.
+type CodeCache interface {
+	// Load returns the code of the key.
+	// It returns nil if not found.
+	Load(key string) (*CachedCode, error)
+	// Save stores the code of the key.
+	Save(key string, code *CachedCode) error
+	// Remove deletes the code of the key.
+	// It returns nil if not found.
+	Remove(key string) error
+}
+
+// CachedCode represents an authorization code which has not been exchanged yet.
+type CachedCode struct {
+	Code string `json:"code"`
+	// Redirect URL used in the authorization request.
+	// The token request must have the same one.
+	RedirectURL string    `json:"redirect_url"`
+	ReceivedAt  time.Time `json:"received_at"`
+}
+
+// NewFileCodeCache returns a CodeCache which stores the codes in the directory.
+// Each code is written to a file readable only by the owner.
+func NewFileCodeCache(dir string) CodeCache {
+	return &fileCodeCache{dir: dir}
+}
+
+type fileCodeCache struct {
+	dir string
+}
+
+func (c *fileCodeCache) filename(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+func (c *fileCodeCache) Load(key string) (*CachedCode, error) {
+	b, err := ioutil.ReadFile(c.filename(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read the cache: %w", err)
+	}
+	var code CachedCode
+	if err := json.Unmarshal(b, &code); err != nil {
+		return nil, fmt.Errorf("invalid cache: %w", err)
+	}
+	return &code, nil
+}
+
+func (c *fileCodeCache) Save(key string, code *CachedCode) error {
+	b, err := json.Marshal(code)
+	if err != nil {
+		return fmt.Errorf("could not encode the code: %w", err)
+	}
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return fmt.Errorf("could not create the cache directory: %w", err)
+	}
+	if err := ioutil.WriteFile(c.filename(key), b, 0600); err != nil {
+		return fmt.Errorf("could not write the cache: %w", err)
+	}
+	return nil
+}
+
+func (c *fileCodeCache) Remove(key string) error {
+	if err := os.Remove(c.filename(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not remove the cache: %w", err)
+	}
+	return nil
+}
+
+// interruptionRecoveryKey returns a key derived from the client ID and scopes.
+func interruptionRecoveryKey(c *Config) string {
+	h := sha256.New()
+	_, _ = h.Write([]byte(c.OAuth2Config.ClientID + "\n" + strings.Join(c.EffectiveScopes(), " ")))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recoverInterruptedCode exchanges the code saved by an interrupted call.
+// It returns nil if no fresh code is found, the hook rejected it or the exchange failed.
+func recoverInterruptedCode(ctx context.Context, c *Config) *oauth2.Token {
+	key := interruptionRecoveryKey(c)
+	cached, err := c.InterruptionRecoveryCache.Load(key)
+	if err != nil || cached == nil {
+		return nil
+	}
+	// a code can be used only once
+	_ = c.InterruptionRecoveryCache.Remove(key)
+	if time.Since(cached.ReceivedAt) > c.InterruptionRecoveryTTL {
+		return nil
+	}
+	if c.ExchangeCodeHook != nil {
+		if err := c.ExchangeCodeHook(ctx, cached.Code); err != nil {
+			return nil
+		}
+	}
+	oauth2Config := c.OAuth2Config
+	oauth2Config.RedirectURL = cached.RedirectURL
+	c.stats.exchangeStarted()
+	token, err := exchangeCode(ctx, c, &oauth2Config, cached.Code)
+	if err != nil {
+		return nil
+	}
+	return token
+}
+
+// saveInterruptedCode saves the code if the context is done before the exchange is completed.
+func saveInterruptedCode(ctx context.Context, c *Config, code string) {
+	if ctx.Err() == nil {
+		return
+	}
+	_ = c.InterruptionRecoveryCache.Save(interruptionRecoveryKey(c), &CachedCode{
+		Code:        code,
+		RedirectURL: c.OAuth2Config.RedirectURL,
+		ReceivedAt:  time.Now(),
+	})
+}
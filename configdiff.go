@@ -0,0 +1,62 @@
+package oauth2cli
+
+import (
+	"reflect"
+)
+
+// ConfigChange represents a changed field of Config.
+type ConfigChange struct {
+	// Name of the field, e.g. OAuth2Config.ClientID.
+	Field    string
+	OldValue interface{}
+	NewValue interface{}
+}
+
+// DiffConfig returns the changed fields from the old config to the new config,
+// e.g. to detect drift of the config loaded from a file.
+//
+// The fields of nested structs such as OAuth2Config are compared individually.
+// The function fields are skipped, because they are not comparable.
+// A nil slice is equal to an empty slice.
+// Note that the values may contain secrets such as OAuth2Config.ClientSecret.
+func DiffConfig(old, new Config) []ConfigChange {
+	var changes []ConfigChange
+	diffStruct("", reflect.ValueOf(old), reflect.ValueOf(new), &changes)
+	return changes
+}
+
+func diffStruct(prefix string, old, new reflect.Value, changes *[]ConfigChange) {
+	t := old.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			// unexported
+			continue
+		}
+		name := prefix + f.Name
+		o, n := old.Field(i), new.Field(i)
+		switch f.Type.Kind() {
+		case reflect.Func:
+			continue
+		case reflect.Interface:
+			if isFunc(o) || isFunc(n) {
+				continue
+			}
+		case reflect.Struct:
+			diffStruct(name+".", o, n, changes)
+			continue
+		case reflect.Slice, reflect.Map:
+			if o.Len() == 0 && n.Len() == 0 {
+				continue
+			}
+		}
+		if !reflect.DeepEqual(o.Interface(), n.Interface()) {
+			*changes = append(*changes, ConfigChange{Field: name, OldValue: o.Interface(), NewValue: n.Interface()})
+		}
+	}
+}
+
+// isFunc returns true if the interface has a function, such as BrowserOpenerFunc.
+func isFunc(v reflect.Value) bool {
+	return !v.IsNil() && v.Elem().Kind() == reflect.Func
+}
@@ -0,0 +1,56 @@
+package oauth2cli
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/oauth2"
+)
+
+func TestDiffConfig(t *testing.T) {
+	t.Run("NoChange", func(t *testing.T) {
+		old := Config{
+			OAuth2Config:          oauth2.Config{ClientID: "YOUR_CLIENT_ID"},
+			AdditionalScopes:      nil,
+			LocalServerMiddleware: func(h http.Handler) http.Handler { return h },
+			BrowserOpener:         BrowserOpenerFunc(func(string) error { return nil }),
+		}
+		new := Config{
+			OAuth2Config:          oauth2.Config{ClientID: "YOUR_CLIENT_ID"},
+			AdditionalScopes:      []string{},
+			LocalServerMiddleware: func(h http.Handler) http.Handler { return h },
+			BrowserOpener:         BrowserOpenerFunc(func(string) error { return nil }),
+		}
+		if changes := DiffConfig(old, new); len(changes) != 0 {
+			t.Errorf("DiffConfig wants no change but was %+v", changes)
+		}
+	})
+
+	t.Run("Changed", func(t *testing.T) {
+		old := Config{
+			OAuth2Config: oauth2.Config{
+				ClientID: "YOUR_CLIENT_ID",
+				Endpoint: oauth2.Endpoint{AuthURL: "https://example.com/auth"},
+				Scopes:   []string{"openid"},
+			},
+		}
+		new := Config{
+			OAuth2Config: oauth2.Config{
+				ClientID: "ANOTHER_CLIENT_ID",
+				Endpoint: oauth2.Endpoint{AuthURL: "https://example.com/auth"},
+				Scopes:   []string{"openid", "email"},
+			},
+			TokenExpirySlack: time.Minute,
+		}
+		want := []ConfigChange{
+			{Field: "OAuth2Config.ClientID", OldValue: "YOUR_CLIENT_ID", NewValue: "ANOTHER_CLIENT_ID"},
+			{Field: "OAuth2Config.Scopes", OldValue: []string{"openid"}, NewValue: []string{"openid", "email"}},
+			{Field: "TokenExpirySlack", OldValue: time.Duration(0), NewValue: time.Minute},
+		}
+		if diff := cmp.Diff(want, DiffConfig(old, new)); diff != "" {
+			t.Errorf("DiffConfig mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
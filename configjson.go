@@ -0,0 +1,248 @@
+package oauth2cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// configJSON represents the serializable fields of Config.
+type configJSON struct {
+	ClientID     string           `json:"client_id,omitempty"`
+	ClientSecret string           `json:"client_secret,omitempty"`
+	AuthURL      string           `json:"auth_url,omitempty"`
+	TokenURL     string           `json:"token_url,omitempty"`
+	AuthStyle    oauth2.AuthStyle `json:"auth_style,omitempty"`
+	RedirectURL  string           `json:"redirect_url,omitempty"`
+	Scopes       []string         `json:"scopes,omitempty"`
+
+	ResponseType               string `json:"response_type,omitempty"`
+	SkipResponseTypeValidation bool   `json:"skip_response_type_validation,omitempty"`
+
+	AuthorizationDetails []AuthorizationDetail `json:"authorization_details,omitempty"`
+
+	TokenResponseJWT       bool   `json:"token_response_jwt,omitempty"`
+	TokenResponseJWKSURL   string `json:"token_response_jwks_url,omitempty"`
+	TokenResponseJWTIssuer string `json:"token_response_jwt_issuer,omitempty"`
+
+	RedirectURLHostname     string       `json:"redirect_url_hostname,omitempty"`
+	AdditionalScopes        []string     `json:"additional_scopes,omitempty"`
+	PKCERegistrationEnabled bool         `json:"pkce_registration_enabled,omitempty"`
+	PKCERegisteredMethods   []string     `json:"pkce_registered_methods,omitempty"`
+	AuthorizationTimeout    jsonDuration `json:"authorization_timeout,omitempty"`
+	HandleOSSignals         bool         `json:"handle_os_signals,omitempty"`
+	TokenEndpointTimeout    jsonDuration `json:"token_endpoint_timeout,omitempty"`
+	TokenEndpointKeepAlive  jsonDuration `json:"token_endpoint_keep_alive,omitempty"`
+	EnableNetTrace          bool         `json:"enable_net_trace,omitempty"`
+	StatsInterval           jsonDuration `json:"stats_interval,omitempty"`
+	TokenExpirySlack        jsonDuration `json:"token_expiry_slack,omitempty"`
+	State                   string       `json:"state,omitempty"`
+	StateLength             int          `json:"state_length,omitempty"`
+	StateMaxLength          int          `json:"state_max_length,omitempty"`
+	InterruptionRecoveryTTL jsonDuration `json:"interruption_recovery_ttl,omitempty"`
+	HybridResponseType      string       `json:"hybrid_response_type,omitempty"`
+	ValidateCHash           bool         `json:"validate_c_hash,omitempty"`
+
+	LocalServerBindAddress          []string     `json:"local_server_bind_address,omitempty"`
+	LocalServerFIFOPath             string       `json:"local_server_fifo_path,omitempty"`
+	LocalServerCertFile             string       `json:"local_server_cert_file,omitempty"`
+	LocalServerKeyFile              string       `json:"local_server_key_file,omitempty"`
+	LocalServerScheme               string       `json:"local_server_scheme,omitempty"`
+	LocalServerProxyProto           bool         `json:"local_server_proxy_proto,omitempty"`
+	LocalServerVerboseHeaders       bool         `json:"local_server_verbose_headers,omitempty"`
+	LocalServerTCPKeepAlive         jsonDuration `json:"local_server_tcp_keep_alive,omitempty"`
+	LocalServerAcceptTimeout        jsonDuration `json:"local_server_accept_timeout,omitempty"`
+	LocalServerRandomizePath        bool         `json:"local_server_randomize_path,omitempty"`
+	LocalServerSuccessHTML          string       `json:"local_server_success_html,omitempty"`
+	LocalServerErrorHTML            string       `json:"local_server_error_html,omitempty"`
+	LocalServerTimeoutHTML          string       `json:"local_server_timeout_html,omitempty"`
+	LocalServerAlreadyUsedHTML      string       `json:"local_server_already_used_html,omitempty"`
+	LocalServerSuppressWindowClose  bool         `json:"local_server_suppress_window_close,omitempty"`
+	LocalServerSingleUse            *bool        `json:"local_server_single_use,omitempty"`
+	AllowDuplicateState             bool         `json:"allow_duplicate_state,omitempty"`
+	LocalServerCORSOrigins          []string     `json:"local_server_cors_origins,omitempty"`
+	LocalServerCORSAllowCredentials bool         `json:"local_server_cors_allow_credentials,omitempty"`
+	LocalServerResponseBodyLimit    int64        `json:"local_server_response_body_limit,omitempty"`
+	LocalServerRequestTimeout       jsonDuration `json:"local_server_request_timeout,omitempty"`
+	LocalServerEarlyClose           bool         `json:"local_server_early_close,omitempty"`
+	CopyAuthURLToClipboard          bool         `json:"copy_auth_url_to_clipboard,omitempty"`
+
+	LocalServerAddress string `json:"local_server_address,omitempty"`
+	LocalServerPort    []int  `json:"local_server_port,omitempty"`
+}
+
+// jsonDuration is a time.Duration encoded as a string such as "1m30s".
+type jsonDuration time.Duration
+
+func (d jsonDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *jsonDuration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string: %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = jsonDuration(v)
+	return nil
+}
+
+// ConfigToJSON returns the JSON representation of the config.
+//
+// The fields which are not serializable are omitted,
+// i.e. functions, channels, interfaces, AuthCodeOptions and TokenRequestOptions.
+// The PKCE verifier is never included, because it is in TokenRequestOptions.
+// If redactSecrets is true, OAuth2Config.ClientSecret and State are omitted.
+func ConfigToJSON(c Config, redactSecrets bool) ([]byte, error) {
+	j := configJSON{
+		ClientID:     c.OAuth2Config.ClientID,
+		ClientSecret: c.OAuth2Config.ClientSecret,
+		AuthURL:      c.OAuth2Config.Endpoint.AuthURL,
+		TokenURL:     c.OAuth2Config.Endpoint.TokenURL,
+		AuthStyle:    c.OAuth2Config.Endpoint.AuthStyle,
+		RedirectURL:  c.OAuth2Config.RedirectURL,
+		Scopes:       c.OAuth2Config.Scopes,
+
+		ResponseType:               c.ResponseType,
+		SkipResponseTypeValidation: c.SkipResponseTypeValidation,
+
+		AuthorizationDetails: c.AuthorizationDetails,
+
+		TokenResponseJWT:       c.TokenResponseJWT,
+		TokenResponseJWKSURL:   c.TokenResponseJWKSURL,
+		TokenResponseJWTIssuer: c.TokenResponseJWTIssuer,
+
+		RedirectURLHostname:     c.RedirectURLHostname,
+		AdditionalScopes:        c.AdditionalScopes,
+		PKCERegistrationEnabled: c.PKCERegistrationEnabled,
+		PKCERegisteredMethods:   c.PKCERegisteredMethods,
+		AuthorizationTimeout:    jsonDuration(c.AuthorizationTimeout),
+		HandleOSSignals:         c.HandleOSSignals,
+		TokenEndpointTimeout:    jsonDuration(c.TokenEndpointTimeout),
+		TokenEndpointKeepAlive:  jsonDuration(c.TokenEndpointKeepAlive),
+		EnableNetTrace:          c.EnableNetTrace,
+		StatsInterval:           jsonDuration(c.StatsInterval),
+		TokenExpirySlack:        jsonDuration(c.TokenExpirySlack),
+		State:                   c.State,
+		StateLength:             c.StateLength,
+		StateMaxLength:          c.StateMaxLength,
+		InterruptionRecoveryTTL: jsonDuration(c.InterruptionRecoveryTTL),
+		HybridResponseType:      c.HybridResponseType,
+		ValidateCHash:           c.ValidateCHash,
+
+		LocalServerBindAddress:          c.LocalServerBindAddress,
+		LocalServerFIFOPath:             c.LocalServerFIFOPath,
+		LocalServerCertFile:             c.LocalServerCertFile,
+		LocalServerKeyFile:              c.LocalServerKeyFile,
+		LocalServerScheme:               c.LocalServerScheme,
+		LocalServerProxyProto:           c.LocalServerProxyProto,
+		LocalServerVerboseHeaders:       c.LocalServerVerboseHeaders,
+		LocalServerTCPKeepAlive:         jsonDuration(c.LocalServerTCPKeepAlive),
+		LocalServerAcceptTimeout:        jsonDuration(c.LocalServerAcceptTimeout),
+		LocalServerRandomizePath:        c.LocalServerRandomizePath,
+		LocalServerSuccessHTML:          c.LocalServerSuccessHTML,
+		LocalServerErrorHTML:            c.LocalServerErrorHTML,
+		LocalServerTimeoutHTML:          c.LocalServerTimeoutHTML,
+		LocalServerAlreadyUsedHTML:      c.LocalServerAlreadyUsedHTML,
+		LocalServerSuppressWindowClose:  c.LocalServerSuppressWindowClose,
+		LocalServerSingleUse:            c.LocalServerSingleUse,
+		AllowDuplicateState:             c.AllowDuplicateState,
+		LocalServerCORSOrigins:          c.LocalServerCORSOrigins,
+		LocalServerCORSAllowCredentials: c.LocalServerCORSAllowCredentials,
+		LocalServerResponseBodyLimit:    c.LocalServerResponseBodyLimit,
+		LocalServerRequestTimeout:       jsonDuration(c.LocalServerRequestTimeout),
+		LocalServerEarlyClose:           c.LocalServerEarlyClose,
+		CopyAuthURLToClipboard:          c.CopyAuthURLToClipboard,
+
+		LocalServerAddress: c.LocalServerAddress,
+		LocalServerPort:    c.LocalServerPort,
+	}
+	if redactSecrets {
+		j.ClientSecret = ""
+		j.State = ""
+	}
+	return json.Marshal(&j)
+}
+
+// MarshalJSON returns the JSON representation of the config with the secrets omitted.
+// See ConfigToJSON for details.
+func (c Config) MarshalJSON() ([]byte, error) {
+	return ConfigToJSON(c, true)
+}
+
+// UnmarshalJSON sets the fields of the config from the JSON representation.
+// The fields which are not serializable are left as they are.
+func (c *Config) UnmarshalJSON(b []byte) error {
+	var j configJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	c.OAuth2Config.ClientID = j.ClientID
+	c.OAuth2Config.ClientSecret = j.ClientSecret
+	c.OAuth2Config.Endpoint.AuthURL = j.AuthURL
+	c.OAuth2Config.Endpoint.TokenURL = j.TokenURL
+	c.OAuth2Config.Endpoint.AuthStyle = j.AuthStyle
+	c.OAuth2Config.RedirectURL = j.RedirectURL
+	c.OAuth2Config.Scopes = j.Scopes
+
+	c.ResponseType = j.ResponseType
+	c.SkipResponseTypeValidation = j.SkipResponseTypeValidation
+
+	c.AuthorizationDetails = j.AuthorizationDetails
+
+	c.TokenResponseJWT = j.TokenResponseJWT
+	c.TokenResponseJWKSURL = j.TokenResponseJWKSURL
+	c.TokenResponseJWTIssuer = j.TokenResponseJWTIssuer
+
+	c.RedirectURLHostname = j.RedirectURLHostname
+	c.AdditionalScopes = j.AdditionalScopes
+	c.PKCERegistrationEnabled = j.PKCERegistrationEnabled
+	c.PKCERegisteredMethods = j.PKCERegisteredMethods
+	c.AuthorizationTimeout = time.Duration(j.AuthorizationTimeout)
+	c.HandleOSSignals = j.HandleOSSignals
+	c.TokenEndpointTimeout = time.Duration(j.TokenEndpointTimeout)
+	c.TokenEndpointKeepAlive = time.Duration(j.TokenEndpointKeepAlive)
+	c.EnableNetTrace = j.EnableNetTrace
+	c.StatsInterval = time.Duration(j.StatsInterval)
+	c.TokenExpirySlack = time.Duration(j.TokenExpirySlack)
+	c.State = j.State
+	c.StateLength = j.StateLength
+	c.StateMaxLength = j.StateMaxLength
+	c.InterruptionRecoveryTTL = time.Duration(j.InterruptionRecoveryTTL)
+	c.HybridResponseType = j.HybridResponseType
+	c.ValidateCHash = j.ValidateCHash
+
+	c.LocalServerBindAddress = j.LocalServerBindAddress
+	c.LocalServerFIFOPath = j.LocalServerFIFOPath
+	c.LocalServerCertFile = j.LocalServerCertFile
+	c.LocalServerKeyFile = j.LocalServerKeyFile
+	c.LocalServerScheme = j.LocalServerScheme
+	c.LocalServerProxyProto = j.LocalServerProxyProto
+	c.LocalServerVerboseHeaders = j.LocalServerVerboseHeaders
+	c.LocalServerTCPKeepAlive = time.Duration(j.LocalServerTCPKeepAlive)
+	c.LocalServerAcceptTimeout = time.Duration(j.LocalServerAcceptTimeout)
+	c.LocalServerRandomizePath = j.LocalServerRandomizePath
+	c.LocalServerSuccessHTML = j.LocalServerSuccessHTML
+	c.LocalServerErrorHTML = j.LocalServerErrorHTML
+	c.LocalServerTimeoutHTML = j.LocalServerTimeoutHTML
+	c.LocalServerAlreadyUsedHTML = j.LocalServerAlreadyUsedHTML
+	c.LocalServerSuppressWindowClose = j.LocalServerSuppressWindowClose
+	c.LocalServerSingleUse = j.LocalServerSingleUse
+	c.AllowDuplicateState = j.AllowDuplicateState
+	c.LocalServerCORSOrigins = j.LocalServerCORSOrigins
+	c.LocalServerCORSAllowCredentials = j.LocalServerCORSAllowCredentials
+	c.LocalServerResponseBodyLimit = j.LocalServerResponseBodyLimit
+	c.LocalServerRequestTimeout = time.Duration(j.LocalServerRequestTimeout)
+	c.LocalServerEarlyClose = j.LocalServerEarlyClose
+	c.CopyAuthURLToClipboard = j.CopyAuthURLToClipboard
+
+	c.LocalServerAddress = j.LocalServerAddress
+	c.LocalServerPort = j.LocalServerPort
+	return nil
+}
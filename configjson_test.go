@@ -0,0 +1,80 @@
+package oauth2cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/oauth2"
+)
+
+func TestConfigToJSON(t *testing.T) {
+	cfg := Config{
+		OAuth2Config: oauth2.Config{
+			ClientID:     "YOUR_CLIENT_ID",
+			ClientSecret: "YOUR_CLIENT_SECRET",
+			Endpoint:     oauth2.Endpoint{AuthURL: "https://example.com/auth", TokenURL: "https://example.com/token"},
+			Scopes:       []string{"openid", "email"},
+		},
+		State:                  "YOUR_STATE",
+		StateLength:            16,
+		TokenExpirySlack:       30 * time.Second,
+		LocalServerBindAddress: []string{"127.0.0.1:8000"},
+		LocalServerSingleUse:   boolPtr(false),
+		LocalServerMiddleware:  func(h http.Handler) http.Handler { return h },
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		b, err := ConfigToJSON(cfg, false)
+		if err != nil {
+			t.Fatalf("ConfigToJSON error: %s", err)
+		}
+		if !strings.Contains(string(b), `"token_expiry_slack":"30s"`) {
+			t.Errorf("duration wants a string but was %s", b)
+		}
+		var got Config
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatalf("Unmarshal error: %s", err)
+		}
+		if got.LocalServerMiddleware != nil {
+			t.Errorf("LocalServerMiddleware wants nil")
+		}
+		got.LocalServerMiddleware = nil
+		want := cfg
+		want.LocalServerMiddleware = nil
+		if diff := cmp.Diff(want, got, cmp.AllowUnexported(Config{}, testingConfig{})); diff != "" {
+			t.Errorf("config mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("RedactSecrets", func(t *testing.T) {
+		for _, marshal := range []func() ([]byte, error){
+			func() ([]byte, error) { return ConfigToJSON(cfg, true) },
+			func() ([]byte, error) { return json.Marshal(cfg) },
+		} {
+			b, err := marshal()
+			if err != nil {
+				t.Fatalf("marshal error: %s", err)
+			}
+			if strings.Contains(string(b), "YOUR_CLIENT_SECRET") {
+				t.Errorf("secret wants to be redacted but was %s", b)
+			}
+			if strings.Contains(string(b), "YOUR_STATE") {
+				t.Errorf("state wants to be redacted but was %s", b)
+			}
+			if !strings.Contains(string(b), "YOUR_CLIENT_ID") {
+				t.Errorf("client ID wants to be included but was %s", b)
+			}
+		}
+	})
+
+	t.Run("InvalidDuration", func(t *testing.T) {
+		var got Config
+		if err := json.Unmarshal([]byte(`{"token_expiry_slack":"INVALID"}`), &got); err == nil {
+			t.Errorf("Unmarshal wants error but was nil")
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package oauth2cli
+
+import (
+	"errors"
+	"net/http"
+)
+
+// corsMiddleware returns a handler which adds the CORS headers for the allowed origins.
+// See https://fetch.spec.whatwg.org/#http-cors-protocol
+func corsMiddleware(c *Config, h http.Handler) http.Handler {
+	if len(c.LocalServerCORSOrigins) == 0 {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowOrigin := c.corsAllowOrigin(origin)
+		if allowOrigin != "" {
+			w.Header().Add("Vary", "Origin")
+			w.Header().Set("Access-Control-Allow-Origin", allowOrigin)
+			if c.LocalServerCORSAllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+		if r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
+			// preflight request
+			if allowOrigin == "" {
+				c.requestLogger(r).Warn("origin of the preflight request is not allowed",
+					"origin", origin, "remoteAddr", r.RemoteAddr)
+				http.Error(w, "forbidden", 403)
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+			if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+			}
+			w.WriteHeader(204)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// corsAllowOrigin returns the value of Access-Control-Allow-Origin for the origin.
+// It returns empty if the origin is not allowed.
+func (c *Config) corsAllowOrigin(origin string) string {
+	if origin == "" {
+		return ""
+	}
+	for _, allowed := range c.LocalServerCORSOrigins {
+		if allowed == origin {
+			return origin
+		}
+		if allowed == "*" {
+			return "*"
+		}
+	}
+	return ""
+}
+
+func (c *Config) validateCORS() error {
+	if !c.LocalServerCORSAllowCredentials {
+		return nil
+	}
+	if len(c.LocalServerCORSOrigins) == 0 {
+		return errors.New("LocalServerCORSAllowCredentials requires LocalServerCORSOrigins")
+	}
+	for _, origin := range c.LocalServerCORSOrigins {
+		if origin == "*" {
+			return errors.New("LocalServerCORSOrigins must not contain * if LocalServerCORSAllowCredentials is set")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package oauth2cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	okHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for _, c := range []struct {
+		name            string
+		cfg             Config
+		method          string
+		origin          string
+		wantStatus      int
+		wantOrigin      string
+		wantCredentials string
+	}{
+		{"NoCORS", Config{}, "GET", "https://app.example.com", 200, "", ""},
+		{"AllowedOrigin", Config{LocalServerCORSOrigins: []string{"https://app.example.com"}}, "GET", "https://app.example.com", 200, "https://app.example.com", ""},
+		{"Wildcard", Config{LocalServerCORSOrigins: []string{"*"}}, "GET", "https://app.example.com", 200, "*", ""},
+		{"DisallowedOrigin", Config{LocalServerCORSOrigins: []string{"https://app.example.com"}}, "GET", "https://evil.example.com", 200, "", ""},
+		{"Credentials", Config{
+			LocalServerCORSOrigins:          []string{"https://app.example.com"},
+			LocalServerCORSAllowCredentials: true,
+		}, "GET", "https://app.example.com", 200, "https://app.example.com", "true"},
+		{"Preflight", Config{LocalServerCORSOrigins: []string{"https://app.example.com"}}, "OPTIONS", "https://app.example.com", 204, "https://app.example.com", ""},
+		{"PreflightDisallowed", Config{LocalServerCORSOrigins: []string{"https://app.example.com"}}, "OPTIONS", "https://evil.example.com", 403, "", ""},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(c.method, "/", nil)
+			r.Header.Set("Origin", c.origin)
+			if c.method == "OPTIONS" {
+				r.Header.Set("Access-Control-Request-Method", "POST")
+			}
+			w := httptest.NewRecorder()
+			corsMiddleware(&c.cfg, okHandler).ServeHTTP(w, r)
+			if w.Code != c.wantStatus {
+				t.Errorf("status wants %d but was %d", c.wantStatus, w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != c.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin wants %q but was %q", c.wantOrigin, got)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != c.wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials wants %q but was %q", c.wantCredentials, got)
+			}
+		})
+	}
+}
+
+func TestConfig_validateCORS(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"NoCredentials", Config{}, false},
+		{"CredentialsWithOrigins", Config{LocalServerCORSOrigins: []string{"https://app.example.com"}, LocalServerCORSAllowCredentials: true}, false},
+		{"CredentialsWithoutOrigins", Config{LocalServerCORSAllowCredentials: true}, true},
+		{"CredentialsWithWildcard", Config{LocalServerCORSOrigins: []string{"*"}, LocalServerCORSAllowCredentials: true}, true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.cfg.validateCORS()
+			if (err != nil) != c.wantErr {
+				t.Errorf("validateCORS wants error=%v but was %v", c.wantErr, err)
+			}
+		})
+	}
+}
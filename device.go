@@ -0,0 +1,300 @@
+package oauth2cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/browser"
+	"golang.org/x/oauth2"
+)
+
+var openBrowser = browser.OpenURL
+
+const defaultBrowserOpenTimeout = 10 * time.Second
+
+// DeviceAuthConfig represents a config for GetTokenWithDeviceAuth.
+type DeviceAuthConfig struct {
+	// URL of the device authorization endpoint.
+	// See https://tools.ietf.org/html/rfc8628#section-3.1
+	DeviceAuthorizationURL string
+	// Timeout to open the browser in GetTokenOrDeviceFallback.
+	// Default to 10 seconds.
+	BrowserOpenTimeout time.Duration
+	// A function to show the user code and verification URI to the user.
+	// Default to print them to stderr.
+	PromptUser func(r DeviceAuthorizationResponse)
+}
+
+// DeviceAuthorizationResponse represents a response from the device authorization endpoint.
+// See https://tools.ietf.org/html/rfc8628#section-3.2
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval,omitempty"`
+}
+
+func defaultPromptUser(r DeviceAuthorizationResponse) {
+	if r.VerificationURIComplete != "" {
+		_, _ = fmt.Fprintf(os.Stderr, "Open %s and confirm the code %s\n", r.VerificationURIComplete, r.UserCode)
+		return
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "Open %s and enter the code %s\n", r.VerificationURI, r.UserCode)
+}
+
+// GetTokenOrDeviceFallback performs the Authorization Code Grant Flow with a browser,
+// and falls back to the Device Authorization Grant if the browser could not be opened.
+//
+// This opens the browser when the local server is ready, by Config.BrowserOpener if it is set.
+// If it fails or does not finish within DeviceAuthConfig.BrowserOpenTimeout,
+// this stops the local server and then starts the device authorization flow.
+// The device authorization request has the scopes of Config.EffectiveScopes.
+func GetTokenOrDeviceFallback(ctx context.Context, config Config, deviceConfig DeviceAuthConfig) (*oauth2.Token, error) {
+	timeout := deviceConfig.BrowserOpenTimeout
+	if timeout == 0 {
+		timeout = defaultBrowserOpenTimeout
+	}
+	readyCh := make(chan string, 1)
+	userReadyCh := config.LocalServerReadyChan
+	config.LocalServerReadyChan = readyCh
+	// the browser is opened only here, not by GetToken
+	open := openBrowser
+	if config.BrowserOpener != nil {
+		open = config.BrowserOpener.OpenURL
+	}
+	config.BrowserOpener = nil
+
+	type result struct {
+		token *oauth2.Token
+		err   error
+	}
+	browserCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	resultCh := make(chan result, 1)
+	go func() {
+		token, err := GetToken(browserCtx, config)
+		resultCh <- result{token, err}
+	}()
+
+	select {
+	case r := <-resultCh:
+		return r.token, r.err
+	case u := <-readyCh:
+		if userReadyCh != nil {
+			userReadyCh <- u
+		}
+		if err := openBrowserWithTimeout(open, u, timeout); err != nil {
+			// stop the browser flow before starting the device flow
+			cancel()
+			<-resultCh
+			oauth2Config := config.OAuth2Config
+			oauth2Config.Scopes = config.EffectiveScopes()
+			return GetTokenWithDeviceAuth(ctx, oauth2Config, deviceConfig)
+		}
+		r := <-resultCh
+		return r.token, r.err
+	}
+}
+
+func openBrowserWithTimeout(open func(string) error, u string, timeout time.Duration) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- open(u)
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("timed out while opening the browser (%s)", timeout)
+	}
+}
+
+// GetTokenWithDeviceAuth performs the Device Authorization Grant and returns a token received from the provider.
+// See https://tools.ietf.org/html/rfc8628
+//
+// This performs the following steps:
+//
+//  1. Send a device authorization request.
+//  2. Show the user code and verification URI to the user.
+//  3. Poll the token endpoint until the user authorizes the device.
+//  4. Return the token.
+func GetTokenWithDeviceAuth(ctx context.Context, oauth2Config oauth2.Config, deviceConfig DeviceAuthConfig) (*oauth2.Token, error) {
+	if deviceConfig.DeviceAuthorizationURL == "" {
+		return nil, errors.New("invalid config: DeviceAuthorizationURL must be set")
+	}
+	promptUser := deviceConfig.PromptUser
+	if promptUser == nil {
+		promptUser = defaultPromptUser
+	}
+	da, err := requestDeviceAuthorization(ctx, &oauth2Config, deviceConfig.DeviceAuthorizationURL)
+	if err != nil {
+		return nil, fmt.Errorf("device authorization error: %w", err)
+	}
+	promptUser(*da)
+	token, err := pollDeviceAccessToken(ctx, &oauth2Config, da)
+	if err != nil {
+		return nil, fmt.Errorf("could not get a token: %w", err)
+	}
+	return token, nil
+}
+
+func requestDeviceAuthorization(ctx context.Context, c *oauth2.Config, deviceAuthorizationURL string) (*DeviceAuthorizationResponse, error) {
+	v := url.Values{}
+	if len(c.Scopes) > 0 {
+		v.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	status, b, err := postForm(ctx, c, deviceAuthorizationURL, v)
+	if err != nil {
+		return nil, err
+	}
+	if status != 200 {
+		return nil, newDeviceErrorResponse(status, b)
+	}
+	var da DeviceAuthorizationResponse
+	if err := json.Unmarshal(b, &da); err != nil {
+		return nil, fmt.Errorf("invalid device authorization response: %w", err)
+	}
+	if da.DeviceCode == "" {
+		return nil, errors.New("device_code is missing in the device authorization response")
+	}
+	return &da, nil
+}
+
+func pollDeviceAccessToken(ctx context.Context, c *oauth2.Config, da *DeviceAuthorizationResponse) (*oauth2.Token, error) {
+	interval := time.Duration(da.Interval) * time.Second
+	if interval == 0 {
+		interval = 5 * time.Second
+	}
+	var expiry <-chan time.Time
+	if da.ExpiresIn > 0 {
+		expiry = time.After(time.Duration(da.ExpiresIn) * time.Second)
+	}
+	v := url.Values{
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		"device_code": {da.DeviceCode},
+	}
+	for {
+		select {
+		case <-time.After(interval):
+		case <-expiry:
+			return nil, errors.New("device code expired while waiting for authorization")
+		case <-ctx.Done():
+			return nil, fmt.Errorf("context done while waiting for authorization: %w", ctx.Err())
+		}
+		status, b, err := postForm(ctx, c, c.Endpoint.TokenURL, v)
+		if err != nil {
+			return nil, err
+		}
+		if status == 200 {
+			return parseTokenResponse(b)
+		}
+		errResp := newDeviceErrorResponse(status, b)
+		switch errResp.ErrorCode {
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			// https://tools.ietf.org/html/rfc8628#section-3.5
+			interval += 5 * time.Second
+			continue
+		}
+		return nil, errResp
+	}
+}
+
+// DeviceErrorResponse represents an error response from the device authorization endpoint or token endpoint.
+type DeviceErrorResponse struct {
+	StatusCode       int
+	ErrorCode        string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (e *DeviceErrorResponse) Error() string {
+	if e.ErrorCode == "" {
+		return fmt.Sprintf("error response from server (status %d)", e.StatusCode)
+	}
+	return fmt.Sprintf("error response from server: %s %s", e.ErrorCode, e.ErrorDescription)
+}
+
+func newDeviceErrorResponse(status int, b []byte) *DeviceErrorResponse {
+	e := DeviceErrorResponse{StatusCode: status}
+	_ = json.Unmarshal(b, &e)
+	return &e
+}
+
+func postForm(ctx context.Context, c *oauth2.Config, endpoint string, v url.Values) (int, []byte, error) {
+	form := url.Values{}
+	for k, vs := range v {
+		form[k] = vs
+	}
+	if c.Endpoint.AuthStyle != oauth2.AuthStyleInHeader {
+		form.Set("client_id", c.ClientID)
+		if c.ClientSecret != "" {
+			form.Set("client_secret", c.ClientSecret)
+		}
+	}
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not create a request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.Endpoint.AuthStyle == oauth2.AuthStyleInHeader {
+		req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+	}
+	resp, err := contextClient(ctx).Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not send a request: %w", err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("could not read the response body: %w", err)
+	}
+	return resp.StatusCode, b, nil
+}
+
+// contextClient returns the HTTP client in the context, as well as golang.org/x/oauth2.
+func contextClient(ctx context.Context) *http.Client {
+	if c, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok && c != nil {
+		return c
+	}
+	return http.DefaultClient
+}
+
+func parseTokenResponse(b []byte) (*oauth2.Token, error) {
+	var resp struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return nil, fmt.Errorf("invalid token response: %w", err)
+	}
+	if resp.AccessToken == "" {
+		return nil, errors.New("access_token is missing in the token response")
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("invalid token response: %w", err)
+	}
+	token := &oauth2.Token{
+		AccessToken:  resp.AccessToken,
+		TokenType:    resp.TokenType,
+		RefreshToken: resp.RefreshToken,
+	}
+	if resp.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	return token.WithExtra(raw), nil
+}
@@ -0,0 +1,147 @@
+package oauth2cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func newDeviceAuthServer(t *testing.T, pendingCount int) *httptest.Server {
+	var polls int
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("could not parse form: %s", err)
+		}
+		if w := "YOUR_CLIENT_ID"; r.Form.Get("client_id") != w {
+			t.Errorf("client_id wants %s but was %s", w, r.Form.Get("client_id"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/device":
+			if w := "email profile"; r.Form.Get("scope") != w {
+				t.Errorf("scope wants %s but was %s", w, r.Form.Get("scope"))
+			}
+			_, _ = fmt.Fprint(w, `{"device_code":"DEVICE_CODE","user_code":"USER_CODE","verification_uri":"https://example.com/device","expires_in":60,"interval":1}`)
+		case "/token":
+			if w := "DEVICE_CODE"; r.Form.Get("device_code") != w {
+				t.Errorf("device_code wants %s but was %s", w, r.Form.Get("device_code"))
+			}
+			polls++
+			if polls <= pendingCount {
+				w.WriteHeader(400)
+				_, _ = fmt.Fprint(w, `{"error":"authorization_pending"}`)
+				return
+			}
+			_, _ = fmt.Fprint(w, `{"access_token":"ACCESS_TOKEN","token_type":"Bearer","expires_in":3600,"refresh_token":"REFRESH_TOKEN"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestGetTokenWithDeviceAuth(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	s := newDeviceAuthServer(t, 1)
+	defer s.Close()
+
+	var prompted DeviceAuthorizationResponse
+	token, err := GetTokenWithDeviceAuth(ctx, oauth2.Config{
+		ClientID: "YOUR_CLIENT_ID",
+		Endpoint: oauth2.Endpoint{TokenURL: s.URL + "/token"},
+		Scopes:   []string{"email", "profile"},
+	}, DeviceAuthConfig{
+		DeviceAuthorizationURL: s.URL + "/device",
+		PromptUser:             func(r DeviceAuthorizationResponse) { prompted = r },
+	})
+	if err != nil {
+		t.Fatalf("GetTokenWithDeviceAuth error: %s", err)
+	}
+	if w := "USER_CODE"; prompted.UserCode != w {
+		t.Errorf("UserCode wants %s but was %s", w, prompted.UserCode)
+	}
+	if w := "ACCESS_TOKEN"; token.AccessToken != w {
+		t.Errorf("AccessToken wants %s but was %s", w, token.AccessToken)
+	}
+	if w := "REFRESH_TOKEN"; token.RefreshToken != w {
+		t.Errorf("RefreshToken wants %s but was %s", w, token.RefreshToken)
+	}
+}
+
+func TestGetTokenOrDeviceFallback(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	s := newDeviceAuthServer(t, 0)
+	defer s.Close()
+	defer func(f func(string) error) { openBrowser = f }(openBrowser)
+	var opened string
+	openBrowser = func(u string) error {
+		opened = u
+		return errors.New("no browser")
+	}
+
+	token, err := GetTokenOrDeviceFallback(ctx, Config{
+		OAuth2Config: oauth2.Config{
+			ClientID: "YOUR_CLIENT_ID",
+			Endpoint: oauth2.Endpoint{AuthURL: s.URL + "/auth", TokenURL: s.URL + "/token"},
+			Scopes:   []string{"email", "profile"},
+		},
+	}, DeviceAuthConfig{
+		DeviceAuthorizationURL: s.URL + "/device",
+		PromptUser:             func(DeviceAuthorizationResponse) {},
+	})
+	if err != nil {
+		t.Fatalf("GetTokenOrDeviceFallback error: %s", err)
+	}
+	if opened == "" {
+		t.Errorf("browser was not opened")
+	}
+	if w := "ACCESS_TOKEN"; token.AccessToken != w {
+		t.Errorf("AccessToken wants %s but was %s", w, token.AccessToken)
+	}
+}
+
+func TestGetTokenOrDeviceFallback_BrowserOpener(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	s := newDeviceAuthServer(t, 0)
+	defer s.Close()
+	defer func(f func(string) error) { openBrowser = f }(openBrowser)
+	openBrowser = func(string) error {
+		t.Errorf("the default browser should not be opened if BrowserOpener is set")
+		return errors.New("no browser")
+	}
+	var opened int32
+	token, err := GetTokenOrDeviceFallback(ctx, Config{
+		OAuth2Config: oauth2.Config{
+			ClientID: "YOUR_CLIENT_ID",
+			Endpoint: oauth2.Endpoint{AuthURL: s.URL + "/auth", TokenURL: s.URL + "/token"},
+			Scopes:   []string{"email"},
+		},
+		// the device request wants the effective scopes
+		AdditionalScopes: []string{"profile"},
+		BrowserOpener: BrowserOpenerFunc(func(string) error {
+			atomic.AddInt32(&opened, 1)
+			return errors.New("no browser")
+		}),
+	}, DeviceAuthConfig{
+		DeviceAuthorizationURL: s.URL + "/device",
+		PromptUser:             func(DeviceAuthorizationResponse) {},
+	})
+	if err != nil {
+		t.Fatalf("GetTokenOrDeviceFallback error: %s", err)
+	}
+	if n := atomic.LoadInt32(&opened); n != 1 {
+		t.Errorf("BrowserOpener wants to be called once but was %d times", n)
+	}
+	if w := "ACCESS_TOKEN"; token.AccessToken != w {
+		t.Errorf("AccessToken wants %s but was %s", w, token.AccessToken)
+	}
+}
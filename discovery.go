@@ -0,0 +1,111 @@
+package oauth2cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// authorizationServerMetadata represents a subset of the discovery document.
+// See https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+// and https://tools.ietf.org/html/rfc8414#section-2
+type authorizationServerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+}
+
+// AutoDetectEndpoint returns the endpoint of the provider which serves the token URL.
+//
+// This derives the issuer candidates from the token URL, from the longest path to the root.
+// For each candidate, this tries the OpenID Connect Discovery (/.well-known/openid-configuration)
+// and then the OAuth 2.0 Authorization Server Metadata (RFC 8414, /.well-known/oauth-authorization-server).
+// A discovery document which has the same token endpoint is preferred.
+//
+// If httpClient is nil, the client in the context is used as well as golang.org/x/oauth2.
+func AutoDetectEndpoint(ctx context.Context, tokenURL string, httpClient *http.Client) (oauth2.Endpoint, error) {
+	u, err := url.Parse(tokenURL)
+	if err != nil {
+		return oauth2.Endpoint{}, fmt.Errorf("invalid token URL: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return oauth2.Endpoint{}, fmt.Errorf("invalid token URL: %s", tokenURL)
+	}
+	if httpClient == nil {
+		httpClient = contextClient(ctx)
+	}
+	var found *authorizationServerMetadata
+	var errs []string
+	for _, metadataURL := range discoveryURLCandidates(u) {
+		m, err := fetchAuthorizationServerMetadata(ctx, httpClient, metadataURL)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if m.TokenEndpoint == tokenURL {
+			found = m
+			break
+		}
+		if found == nil {
+			found = m
+		}
+	}
+	if found == nil {
+		return oauth2.Endpoint{}, fmt.Errorf("no discovery document found: %s", strings.Join(errs, ", "))
+	}
+	return oauth2.Endpoint{
+		AuthURL:  found.AuthorizationEndpoint,
+		TokenURL: tokenURL,
+	}, nil
+}
+
+func discoveryURLCandidates(tokenURL *url.URL) []string {
+	origin := tokenURL.Scheme + "://" + tokenURL.Host
+	segments := strings.Split(strings.Trim(tokenURL.Path, "/"), "/")
+	var candidates []string
+	for i := len(segments) - 1; i >= 0; i-- {
+		issuerPath := strings.Join(segments[:i], "/")
+		if issuerPath != "" {
+			issuerPath = "/" + issuerPath
+		}
+		candidates = append(candidates,
+			origin+issuerPath+"/.well-known/openid-configuration",
+			origin+"/.well-known/oauth-authorization-server"+issuerPath,
+		)
+	}
+	return candidates
+}
+
+func fetchAuthorizationServerMetadata(ctx context.Context, httpClient *http.Client, metadataURL string) (*authorizationServerMetadata, error) {
+	req, err := http.NewRequest("GET", metadataURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create a request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("could not send a request: %w", err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the response body: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("%s returned status %d", metadataURL, resp.StatusCode)
+	}
+	var m authorizationServerMetadata
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("invalid discovery document %s: %w", metadataURL, err)
+	}
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" {
+		return nil, fmt.Errorf("%s has no authorization_endpoint or token_endpoint", metadataURL)
+	}
+	return &m, nil
+}
@@ -0,0 +1,60 @@
+package oauth2cli
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/oauth2"
+)
+
+func TestAutoDetectEndpoint(t *testing.T) {
+	var s *httptest.Server
+	s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/realms/example/.well-known/openid-configuration":
+			_, _ = fmt.Fprintf(w, `{"issuer":"%[1]s/realms/example","authorization_endpoint":"%[1]s/realms/example/protocol/openid-connect/auth","token_endpoint":"%[1]s/realms/example/protocol/openid-connect/token"}`, s.URL)
+		case "/.well-known/oauth-authorization-server/tenant":
+			_, _ = fmt.Fprintf(w, `{"issuer":"%[1]s/tenant","authorization_endpoint":"%[1]s/tenant/authorize","token_endpoint":"%[1]s/tenant/token"}`, s.URL)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+
+	t.Run("OIDCDiscovery", func(t *testing.T) {
+		got, err := AutoDetectEndpoint(context.TODO(), s.URL+"/realms/example/protocol/openid-connect/token", nil)
+		if err != nil {
+			t.Fatalf("AutoDetectEndpoint error: %s", err)
+		}
+		want := oauth2.Endpoint{
+			AuthURL:  s.URL + "/realms/example/protocol/openid-connect/auth",
+			TokenURL: s.URL + "/realms/example/protocol/openid-connect/token",
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("AuthorizationServerMetadata", func(t *testing.T) {
+		got, err := AutoDetectEndpoint(context.TODO(), s.URL+"/tenant/token", s.Client())
+		if err != nil {
+			t.Fatalf("AutoDetectEndpoint error: %s", err)
+		}
+		want := oauth2.Endpoint{
+			AuthURL:  s.URL + "/tenant/authorize",
+			TokenURL: s.URL + "/tenant/token",
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("mismatch (-want +got):\n%s", diff)
+		}
+	})
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := AutoDetectEndpoint(context.TODO(), s.URL+"/unknown/token", nil)
+		if err == nil {
+			t.Errorf("AutoDetectEndpoint wants error but was nil")
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package e2e_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/int128/listener"
+	"github.com/int128/oauth2cli"
+	"github.com/int128/oauth2cli/e2e_test/authserver"
+	"golang.org/x/oauth2"
+)
+
+// TestNoTOCTOURaceWithProbeAndListen verifies that only one of the concurrent calls
+// can bind to a fixed port, even if the port has been probed as free.
+func TestNoTOCTOURaceWithProbeAndListen(t *testing.T) {
+	const concurrency = 100
+	const validTokenResponse = `{"access_token": "ACCESS_TOKEN","token_type": "Bearer","expires_in": 3600,"refresh_token": "REFRESH_TOKEN"}`
+	h := &authserver.Handler{
+		T: t,
+		NewAuthorizationResponse: func(r authserver.AuthorizationRequest) string {
+			return fmt.Sprintf("%s?state=%s&code=%s", r.RedirectURI, r.State, "AUTH_CODE")
+		},
+		NewTokenResponse: func(r authserver.TokenRequest) (int, string) {
+			return 200, validTokenResponse
+		},
+	}
+	s := httptest.NewServer(h)
+	defer s.Close()
+
+	// probe a free port
+	probe, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not probe a port: %s", err)
+	}
+	port := probe.Addr().(*net.TCPAddr).Port
+	if err := probe.Close(); err != nil {
+		t.Fatalf("could not close the probe: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	openBrowserCh := make(chan string, 1)
+	cfg := oauth2cli.Config{
+		OAuth2Config: oauth2.Config{
+			ClientID:     "YOUR_CLIENT_ID",
+			ClientSecret: "YOUR_CLIENT_SECRET",
+			Scopes:       []string{"email", "profile"},
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  s.URL + "/auth",
+				TokenURL: s.URL + "/token",
+			},
+		},
+		LocalServerBindAddress: []string{fmt.Sprintf("127.0.0.1:%d", port)},
+		LocalServerReadyChan:   openBrowserCh,
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var succeeded, addrInUse int
+	failed := make(chan struct{}, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := oauth2cli.GetToken(ctx, cfg)
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				succeeded++
+				return
+			}
+			if isAddrInUse(err) {
+				addrInUse++
+			} else {
+				t.Errorf("GetToken wants EADDRINUSE but was %s", err)
+			}
+			failed <- struct{}{}
+		}()
+	}
+	// the winner holds the port until all the others have failed
+	for i := 0; i < concurrency-1; i++ {
+		select {
+		case <-failed:
+		case <-ctx.Done():
+			t.Fatalf("context done while waiting for the failures: %s", ctx.Err())
+		}
+	}
+	select {
+	case to := <-openBrowserCh:
+		if _, _, err := openBrowserRequest(to); err != nil {
+			t.Errorf("could not open browser request: %s", err)
+		}
+	case <-ctx.Done():
+		t.Fatalf("context done while waiting for the local server: %s", ctx.Err())
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Errorf("number of succeeded calls wants 1 but was %d", succeeded)
+	}
+	if addrInUse != concurrency-1 {
+		t.Errorf("number of EADDRINUSE wants %d but was %d", concurrency-1, addrInUse)
+	}
+}
+
+func isAddrInUse(err error) bool {
+	var noAvailablePortError listener.NoAvailablePortError
+	if !errors.As(err, &noAvailablePortError) {
+		return false
+	}
+	for _, cause := range noAvailablePortError.Causes() {
+		if errors.Is(cause, syscall.EADDRINUSE) {
+			return true
+		}
+	}
+	return false
+}
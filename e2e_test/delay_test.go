@@ -0,0 +1,57 @@
+//go:build oauth2cli_testing
+// +build oauth2cli_testing
+
+package e2e_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/int128/oauth2cli"
+	"github.com/int128/oauth2cli/e2e_test/authserver"
+	"golang.org/x/oauth2"
+)
+
+func TestGetToken_LocalServerResponseDelayForTesting(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 500*time.Millisecond)
+	defer cancel()
+	h := authserver.Handler{
+		T: t,
+		NewAuthorizationResponse: func(r authserver.AuthorizationRequest) string {
+			return fmt.Sprintf("%s?state=%s&code=%s", r.RedirectURI, r.State, "AUTH_CODE")
+		},
+		NewTokenResponse: func(r authserver.TokenRequest) (int, string) {
+			return 500, "should not reach here"
+		},
+	}
+	s := httptest.NewServer(&h)
+	defer s.Close()
+	openBrowserCh := make(chan string, 1)
+	cfg := oauth2cli.Config{
+		OAuth2Config: oauth2.Config{
+			ClientID:     "YOUR_CLIENT_ID",
+			ClientSecret: "YOUR_CLIENT_SECRET",
+			Scopes:       []string{"email", "profile"},
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  s.URL + "/auth",
+				TokenURL: s.URL + "/token",
+			},
+		},
+		LocalServerReadyChan:  openBrowserCh,
+		LocalServerMiddleware: loggingMiddleware(t),
+	}
+	cfg.LocalServerResponseDelayForTesting = 5 * time.Second
+
+	go func() {
+		// the request does not complete until the delay
+		_, _, _ = openBrowserRequest(<-openBrowserCh)
+	}()
+	_, err := oauth2cli.GetToken(ctx, cfg)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetToken wants context.DeadlineExceeded but was %v", err)
+	}
+}
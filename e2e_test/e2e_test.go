@@ -9,6 +9,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -176,6 +177,160 @@ func TestGetToken(t *testing.T) {
 		successfulTest(t, cfg, h)
 	})
 
+	t.Run("SessionStateValidator", func(t *testing.T) {
+		var sessionState string
+		cfg := oauth2cli.Config{
+			OAuth2Config: oauth2.Config{
+				ClientID:     "YOUR_CLIENT_ID",
+				ClientSecret: "YOUR_CLIENT_SECRET",
+				Scopes:       []string{"email", "profile"},
+			},
+			SessionStateValidator: func(s string) error {
+				sessionState = s
+				return nil
+			},
+			LocalServerMiddleware: loggingMiddleware(t),
+		}
+		h := &authserver.Handler{
+			T: t,
+			NewAuthorizationResponse: func(r authserver.AuthorizationRequest) string {
+				return fmt.Sprintf("%s?state=%s&code=%s&session_state=%s", r.RedirectURI, r.State, "AUTH_CODE", "SESSION_STATE")
+			},
+			NewTokenResponse: func(r authserver.TokenRequest) (int, string) {
+				return 200, validTokenResponse
+			},
+		}
+		successfulTest(t, cfg, h)
+		if w := "SESSION_STATE"; sessionState != w {
+			t.Errorf("session_state wants %s but was %s", w, sessionState)
+		}
+	})
+
+	t.Run("SessionStateValidatorError", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+		defer cancel()
+		h := &authserver.Handler{
+			T: t,
+			NewAuthorizationResponse: func(r authserver.AuthorizationRequest) string {
+				return fmt.Sprintf("%s?state=%s&code=%s&session_state=%s", r.RedirectURI, r.State, "AUTH_CODE", "SESSION_STATE")
+			},
+			NewTokenResponse: func(r authserver.TokenRequest) (int, string) {
+				t.Errorf("token request should not be sent")
+				return 500, "should not reach here"
+			},
+		}
+		s := httptest.NewServer(h)
+		defer s.Close()
+		openBrowserCh := make(chan string)
+		defer close(openBrowserCh)
+		cfg := oauth2cli.Config{
+			OAuth2Config: oauth2.Config{
+				ClientID:     "YOUR_CLIENT_ID",
+				ClientSecret: "YOUR_CLIENT_SECRET",
+				Scopes:       []string{"email", "profile"},
+				Endpoint: oauth2.Endpoint{
+					AuthURL:  s.URL + "/auth",
+					TokenURL: s.URL + "/token",
+				},
+			},
+			SessionStateValidator: func(s string) error {
+				return fmt.Errorf("unknown session %s", s)
+			},
+			LocalServerReadyChan:  openBrowserCh,
+			LocalServerMiddleware: loggingMiddleware(t),
+		}
+
+		eg, ctx := errgroup.WithContext(ctx)
+		eg.Go(func() error {
+			// Wait for the local server and open a browser request.
+			select {
+			case to := <-openBrowserCh:
+				status, body, err := openBrowserRequest(to)
+				if err != nil {
+					return fmt.Errorf("could not open browser request: %w", err)
+				}
+				t.Logf("got response body: %s", body)
+				if status != 500 {
+					t.Errorf("status wants 500 but %d", status)
+				}
+				return nil
+			case <-ctx.Done():
+				return fmt.Errorf("context done while waiting for opening browser: %w", ctx.Err())
+			}
+		})
+		eg.Go(func() error {
+			// Start a local server and get a token.
+			_, err := oauth2cli.GetToken(ctx, cfg)
+			if err == nil {
+				return errors.New("GetToken wants error but was nil")
+			}
+			if w := "invalid session_state: unknown session SESSION_STATE"; !strings.Contains(err.Error(), w) {
+				t.Errorf("error wants %q but was %q", w, err)
+			}
+			return nil
+		})
+		if err := eg.Wait(); err != nil {
+			t.Errorf("error: %+v", err)
+		}
+	})
+
+	t.Run("AuthorizationURLValidator", func(t *testing.T) {
+		var authorizationURL *url.URL
+		cfg := oauth2cli.Config{
+			OAuth2Config: oauth2.Config{
+				ClientID:     "YOUR_CLIENT_ID",
+				ClientSecret: "YOUR_CLIENT_SECRET",
+				Scopes:       []string{"email", "profile"},
+			},
+			AuthorizationURLValidator: func(u *url.URL) error {
+				authorizationURL = u
+				return nil
+			},
+			LocalServerMiddleware: loggingMiddleware(t),
+		}
+		h := &authserver.Handler{
+			T: t,
+			NewAuthorizationResponse: func(r authserver.AuthorizationRequest) string {
+				return fmt.Sprintf("%s?state=%s&code=%s", r.RedirectURI, r.State, "AUTH_CODE")
+			},
+			NewTokenResponse: func(r authserver.TokenRequest) (int, string) {
+				return 200, validTokenResponse
+			},
+		}
+		successfulTest(t, cfg, h)
+		if authorizationURL == nil {
+			t.Fatalf("AuthorizationURLValidator was not called")
+		}
+		if w := "email profile"; authorizationURL.Query().Get("scope") != w {
+			t.Errorf("scope wants %s but was %s", w, authorizationURL.Query().Get("scope"))
+		}
+	})
+
+	t.Run("AuthorizationURLValidatorError", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+		defer cancel()
+		cfg := oauth2cli.Config{
+			OAuth2Config: oauth2.Config{
+				ClientID: "YOUR_CLIENT_ID",
+				Endpoint: oauth2.Endpoint{
+					AuthURL:  "http://auth.example.com/auth",
+					TokenURL: "http://auth.example.com/token",
+				},
+			},
+			AuthorizationURLValidator: func(u *url.URL) error {
+				if u.Scheme != "https" {
+					return fmt.Errorf("scheme must be https but was %s", u.Scheme)
+				}
+				return nil
+			},
+		}
+		_, err := oauth2cli.GetToken(ctx, cfg)
+		if err == nil {
+			t.Fatalf("GetToken wants error but was nil")
+		}
+		t.Logf("expected error: %s", err)
+	})
+
 	t.Run("ErrorAuthorizationResponse", func(t *testing.T) {
 		cfg := oauth2cli.Config{
 			OAuth2Config: oauth2.Config{
@@ -0,0 +1,90 @@
+package e2e_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/int128/oauth2cli"
+	"github.com/int128/oauth2cli/e2e_test/authserver"
+	"golang.org/x/oauth2"
+)
+
+func TestGetToken_ExchangeCodeHook(t *testing.T) {
+	const validTokenResponse = `{"access_token": "ACCESS_TOKEN","token_type": "Bearer","expires_in": 3600,"refresh_token": "REFRESH_TOKEN"}`
+	var tokenRequests int
+	h := &authserver.Handler{
+		T: t,
+		NewAuthorizationResponse: func(r authserver.AuthorizationRequest) string {
+			return fmt.Sprintf("%s?state=%s&code=%s", r.RedirectURI, r.State, "AUTH_CODE")
+		},
+		NewTokenResponse: func(r authserver.TokenRequest) (int, string) {
+			tokenRequests++
+			return 200, validTokenResponse
+		},
+	}
+	s := httptest.NewServer(h)
+	defer s.Close()
+	getToken := func(hook func(ctx context.Context, code string) error) (*oauth2.Token, error) {
+		ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+		defer cancel()
+		openBrowserCh := make(chan string, 1)
+		go func() {
+			if _, _, err := openBrowserRequest(<-openBrowserCh); err != nil {
+				t.Errorf("could not open browser request: %s", err)
+			}
+		}()
+		return oauth2cli.GetToken(ctx, oauth2cli.Config{
+			OAuth2Config: oauth2.Config{
+				ClientID:     "YOUR_CLIENT_ID",
+				ClientSecret: "YOUR_CLIENT_SECRET",
+				Scopes:       []string{"email", "profile"},
+				Endpoint: oauth2.Endpoint{
+					AuthURL:  s.URL + "/auth",
+					TokenURL: s.URL + "/token",
+				},
+			},
+			ExchangeCodeHook:      hook,
+			LocalServerReadyChan:  openBrowserCh,
+			LocalServerMiddleware: loggingMiddleware(t),
+		})
+	}
+
+	t.Run("Accept", func(t *testing.T) {
+		tokenRequests = 0
+		var code string
+		token, err := getToken(func(ctx context.Context, c string) error {
+			code = c
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("GetToken error: %s", err)
+		}
+		if w := "AUTH_CODE"; code != w {
+			t.Errorf("code wants %s but was %s", w, code)
+		}
+		if w := "ACCESS_TOKEN"; token.AccessToken != w {
+			t.Errorf("AccessToken wants %s but was %s", w, token.AccessToken)
+		}
+		if tokenRequests != 1 {
+			t.Errorf("tokenRequests wants 1 but was %d", tokenRequests)
+		}
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		tokenRequests = 0
+		_, err := getToken(func(ctx context.Context, c string) error {
+			return errors.New("code is rejected")
+		})
+		if err == nil {
+			t.Fatalf("GetToken wants error but was nil")
+		}
+		t.Logf("expected error: %s", err)
+		if tokenRequests != 0 {
+			t.Errorf("tokenRequests wants 0 but was %d", tokenRequests)
+		}
+	})
+}
@@ -0,0 +1,84 @@
+//go:build integration
+// +build integration
+
+package e2e_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/int128/oauth2cli"
+	"github.com/int128/oauth2cli/oidcflow"
+	oauth2clitesting "github.com/int128/oauth2cli/testing"
+)
+
+// TestGetTokenOIDCEndToEnd performs the flow against a real OpenID Connect provider,
+// such as a public test tenant. It is skipped unless the following variables are set:
+//
+//	OAUTH2CLI_INTEGRATION_ISSUER         issuer URL, e.g. https://demo.c2id.com
+//	OAUTH2CLI_INTEGRATION_CLIENT_ID      client registered with the redirect URI http://localhost:8000
+//	OAUTH2CLI_INTEGRATION_CLIENT_SECRET  client secret (optional for a public client)
+//	OAUTH2CLI_INTEGRATION_COOKIE         session cookie of the provider, e.g. "sid=xxx" (optional)
+//	OAUTH2CLI_INTEGRATION_SUBJECT        expected sub claim (optional)
+//
+// The browser does not fill in a login form, so the provider must authorize
+// without user interaction, e.g. by the session cookie of a user who has consented.
+//
+//	go test -v -tags integration -run TestGetTokenOIDCEndToEnd ./e2e_test/
+func TestGetTokenOIDCEndToEnd(t *testing.T) {
+	issuer, clientID := os.Getenv("OAUTH2CLI_INTEGRATION_ISSUER"), os.Getenv("OAUTH2CLI_INTEGRATION_CLIENT_ID")
+	if issuer == "" || clientID == "" {
+		t.Skip("OAUTH2CLI_INTEGRATION_ISSUER and OAUTH2CLI_INTEGRATION_CLIENT_ID are not set")
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), 30*time.Second)
+	defer cancel()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("could not create a cookie jar: %s", err)
+	}
+	if cookie := os.Getenv("OAUTH2CLI_INTEGRATION_COOKIE"); cookie != "" {
+		issuerURL, err := url.Parse(issuer)
+		if err != nil {
+			t.Fatalf("invalid issuer: %s", err)
+		}
+		req := http.Request{Header: http.Header{"Cookie": {cookie}}}
+		jar.SetCookies(issuerURL, req.Cookies())
+	}
+	browserClient := &http.Client{Jar: jar, Timeout: 10 * time.Second}
+
+	result, err := oidcflow.GetIDToken(ctx, oidcflow.Config{
+		Issuer:       issuer,
+		ClientID:     clientID,
+		ClientSecret: os.Getenv("OAUTH2CLI_INTEGRATION_CLIENT_SECRET"),
+		Scopes:       []string{"email"},
+		GetTokenConfig: oauth2cli.Config{
+			LocalServerBindAddress: []string{"127.0.0.1:8000"},
+			BrowserOpener:          oauth2clitesting.NewAutoNavigatingBrowser(browserClient),
+		},
+	})
+	if err != nil {
+		t.Fatalf("GetIDToken error: %s", err)
+	}
+	if result.Token.AccessToken == "" {
+		t.Errorf("AccessToken wants non-empty")
+	}
+	if iss, _ := result.Claims["iss"].(string); strings.TrimSuffix(iss, "/") != strings.TrimSuffix(issuer, "/") {
+		t.Errorf("iss wants %s but was %s", issuer, iss)
+	}
+	if result.Subject == "" {
+		t.Errorf("sub wants non-empty")
+	}
+	if want := os.Getenv("OAUTH2CLI_INTEGRATION_SUBJECT"); want != "" && result.Subject != want {
+		t.Errorf("sub wants %s but was %s", want, result.Subject)
+	}
+	if !result.Expiry.After(time.Now()) {
+		t.Errorf("exp wants the future but was %s", result.Expiry)
+	}
+}
@@ -0,0 +1,92 @@
+package e2e_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/int128/oauth2cli"
+	"github.com/int128/oauth2cli/e2e_test/authserver"
+	"golang.org/x/oauth2"
+)
+
+func TestGetToken_InterruptionRecoveryCache(t *testing.T) {
+	const validTokenResponse = `{"access_token": "ACCESS_TOKEN","token_type": "Bearer","expires_in": 3600,"refresh_token": "REFRESH_TOKEN"}`
+	dir, err := ioutil.TempDir("", "oauth2cli")
+	if err != nil {
+		t.Fatalf("could not create a temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, interrupt := context.WithTimeout(context.TODO(), 1*time.Second)
+	defer interrupt()
+	var redirectURI string
+	var tokenRequests int
+	h := &authserver.Handler{
+		T: t,
+		NewAuthorizationResponse: func(r authserver.AuthorizationRequest) string {
+			redirectURI = r.RedirectURI
+			return fmt.Sprintf("%s?state=%s&code=%s", r.RedirectURI, r.State, "AUTH_CODE")
+		},
+		NewTokenResponse: func(r authserver.TokenRequest) (int, string) {
+			tokenRequests++
+			if tokenRequests == 1 {
+				// the user interrupts the flow during the exchange
+				interrupt()
+				time.Sleep(100 * time.Millisecond)
+				return 500, "should not reach the client"
+			}
+			if w := redirectURI; r.Raw.Get("redirect_uri") != w {
+				t.Errorf("redirect_uri wants %s but was %s", w, r.Raw.Get("redirect_uri"))
+			}
+			if w := "AUTH_CODE"; r.Code != w {
+				t.Errorf("code wants %s but was %s", w, r.Code)
+			}
+			return 200, validTokenResponse
+		},
+	}
+	s := httptest.NewServer(h)
+	defer s.Close()
+	openBrowserCh := make(chan string, 1)
+	cfg := oauth2cli.Config{
+		OAuth2Config: oauth2.Config{
+			ClientID:     "YOUR_CLIENT_ID",
+			ClientSecret: "YOUR_CLIENT_SECRET",
+			Scopes:       []string{"email", "profile"},
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  s.URL + "/auth",
+				TokenURL: s.URL + "/token",
+			},
+		},
+		InterruptionRecoveryCache: oauth2cli.NewFileCodeCache(dir),
+		LocalServerReadyChan:      openBrowserCh,
+		LocalServerMiddleware:     loggingMiddleware(t),
+	}
+
+	go func() {
+		if _, _, err := openBrowserRequest(<-openBrowserCh); err != nil {
+			t.Errorf("could not open browser request: %s", err)
+		}
+	}()
+	if _, err := oauth2cli.GetToken(ctx, cfg); err == nil {
+		t.Fatalf("GetToken wants error on interruption but was nil")
+	}
+
+	// the next call exchanges the saved code without the browser
+	ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+	defer cancel()
+	token, err := oauth2cli.GetToken(ctx, cfg)
+	if err != nil {
+		t.Fatalf("GetToken error: %s", err)
+	}
+	if w := "ACCESS_TOKEN"; token.AccessToken != w {
+		t.Errorf("AccessToken wants %s but was %s", w, token.AccessToken)
+	}
+	if tokenRequests != 2 {
+		t.Errorf("tokenRequests wants 2 but was %d", tokenRequests)
+	}
+}
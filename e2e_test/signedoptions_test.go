@@ -0,0 +1,88 @@
+package e2e_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/int128/oauth2cli"
+	"github.com/int128/oauth2cli/e2e_test/authserver"
+	"golang.org/x/oauth2"
+)
+
+func TestGetTokenWithSignedOptions(t *testing.T) {
+	const validTokenResponse = `{"access_token": "ACCESS_TOKEN","token_type": "Bearer","expires_in": 3600,"refresh_token": "REFRESH_TOKEN"}`
+	h := &authserver.Handler{
+		T: t,
+		NewAuthorizationResponse: func(r authserver.AuthorizationRequest) string {
+			if w := "SIGNED(" + r.State + ")"; r.Raw.Get("request") != w {
+				t.Errorf("request wants %s but was %s", w, r.Raw.Get("request"))
+			}
+			return fmt.Sprintf("%s?state=%s&code=%s", r.RedirectURI, r.State, "AUTH_CODE")
+		},
+		NewTokenResponse: func(r authserver.TokenRequest) (int, string) {
+			return 200, validTokenResponse
+		},
+	}
+	s := httptest.NewServer(h)
+	defer s.Close()
+	newConfig := func(openBrowserCh chan string) oauth2cli.Config {
+		return oauth2cli.Config{
+			OAuth2Config: oauth2.Config{
+				ClientID:     "YOUR_CLIENT_ID",
+				ClientSecret: "YOUR_CLIENT_SECRET",
+				Scopes:       []string{"email", "profile"},
+				Endpoint: oauth2.Endpoint{
+					AuthURL:  s.URL + "/auth",
+					TokenURL: s.URL + "/token",
+				},
+			},
+			LocalServerReadyChan:  openBrowserCh,
+			LocalServerMiddleware: loggingMiddleware(t),
+		}
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+		defer cancel()
+		openBrowserCh := make(chan string, 1)
+		go func() {
+			if _, _, err := openBrowserRequest(<-openBrowserCh); err != nil {
+				t.Errorf("could not open browser request: %s", err)
+			}
+		}()
+		signer := func(authParams url.Values) ([]oauth2.AuthCodeOption, error) {
+			if !strings.HasPrefix(authParams.Get("redirect_uri"), "http://localhost:") {
+				t.Errorf("redirect_uri wants the local server but was %s", authParams.Get("redirect_uri"))
+			}
+			return []oauth2.AuthCodeOption{
+				oauth2.SetAuthURLParam("request", "SIGNED("+authParams.Get("state")+")"),
+			}, nil
+		}
+		token, err := oauth2cli.GetTokenWithSignedOptions(ctx, newConfig(openBrowserCh), signer)
+		if err != nil {
+			t.Fatalf("GetTokenWithSignedOptions error: %s", err)
+		}
+		if w := "ACCESS_TOKEN"; token.AccessToken != w {
+			t.Errorf("AccessToken wants %s but was %s", w, token.AccessToken)
+		}
+	})
+
+	t.Run("SignerError", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+		defer cancel()
+		signer := func(url.Values) ([]oauth2.AuthCodeOption, error) {
+			return nil, errors.New("signing service unavailable")
+		}
+		_, err := oauth2cli.GetTokenWithSignedOptions(ctx, newConfig(nil), signer)
+		if err == nil {
+			t.Fatalf("GetTokenWithSignedOptions wants error but was nil")
+		}
+		t.Logf("expected error: %s", err)
+	})
+}
@@ -0,0 +1,42 @@
+// This is an example to get a token from GitHub and call the user API.
+//
+// Create an OAuth App at https://github.com/settings/developers with the callback URL http://localhost,
+// and then run:
+//
+//	GITHUB_CLIENT_ID=xxx GITHUB_CLIENT_SECRET=xxx go run ./examples/github
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/int128/oauth2cli"
+	"github.com/int128/oauth2cli/examples/internal/example"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+)
+
+func main() {
+	clientID, clientSecret := os.Getenv("GITHUB_CLIENT_ID"), os.Getenv("GITHUB_CLIENT_SECRET")
+	if clientID == "" {
+		log.Fatalf("You need to set GITHUB_CLIENT_ID and GITHUB_CLIENT_SECRET")
+	}
+	cfg := oauth2cli.Config{
+		OAuth2Config: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Endpoint:     github.Endpoint,
+			Scopes:       []string{"read:user"},
+		},
+	}
+	ctx := context.Background()
+	// get a token with PKCE, or the cached one
+	token, err := example.GetToken(ctx, "github", &cfg)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+	if err := example.CallAPI(ctx, cfg, token, "https://api.github.com/user"); err != nil {
+		log.Fatalf("%s", err)
+	}
+}
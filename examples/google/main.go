@@ -0,0 +1,42 @@
+// This is an example to get a token from Google and call the userinfo API.
+//
+// Create an OAuth client of the desktop app type at https://console.cloud.google.com/apis/credentials,
+// and then run:
+//
+//	GOOGLE_CLIENT_ID=xxx GOOGLE_CLIENT_SECRET=xxx go run ./examples/google
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/int128/oauth2cli"
+	"github.com/int128/oauth2cli/examples/internal/example"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+func main() {
+	clientID, clientSecret := os.Getenv("GOOGLE_CLIENT_ID"), os.Getenv("GOOGLE_CLIENT_SECRET")
+	if clientID == "" {
+		log.Fatalf("You need to set GOOGLE_CLIENT_ID and GOOGLE_CLIENT_SECRET")
+	}
+	cfg := oauth2cli.Config{
+		OAuth2Config: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Endpoint:     google.Endpoint,
+			Scopes:       []string{"openid", "email", "profile"},
+		},
+	}
+	ctx := context.Background()
+	// get a token with PKCE, or the cached one
+	token, err := example.GetToken(ctx, "google", &cfg)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+	if err := example.CallAPI(ctx, cfg, token, "https://openidconnect.googleapis.com/v1/userinfo"); err != nil {
+		log.Fatalf("%s", err)
+	}
+}
@@ -0,0 +1,123 @@
+// Package example provides the common steps of the examples,
+// i.e. getting a token with PKCE, caching it to a file and calling an API.
+package example
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/int128/oauth2cli"
+	"github.com/int128/oauth2cli/oauth2params"
+	"github.com/pkg/browser"
+	"golang.org/x/oauth2"
+	"golang.org/x/sync/errgroup"
+)
+
+// GetToken returns the cached token of the name if present.
+// Otherwise, it gets a token with PKCE via the browser and writes it to the cache.
+// It also sets Config.OnTokenRefreshed to write back a refreshed token.
+func GetToken(ctx context.Context, name string, cfg *oauth2cli.Config) (*oauth2.Token, error) {
+	cfg.OnTokenRefreshed = func(_ context.Context, _, newToken *oauth2.Token) {
+		if err := saveToken(name, newToken); err != nil {
+			log.Printf("could not save the token: %s", err)
+		}
+	}
+	if token, err := loadToken(name); err == nil {
+		return token, nil
+	}
+	token, err := getTokenWithPKCE(ctx, *cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := saveToken(name, token); err != nil {
+		log.Printf("could not save the token: %s", err)
+	}
+	return token, nil
+}
+
+// CallAPI sends a GET request to the URL with the token and prints the response body.
+func CallAPI(ctx context.Context, cfg oauth2cli.Config, token *oauth2.Token, url string) error {
+	client := oauth2.NewClient(ctx, oauth2cli.NewCachedTokenSource(ctx, cfg, token))
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("could not call the API: %w", err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("could not read the response: %w", err)
+	}
+	fmt.Printf("%s\n", b)
+	return nil
+}
+
+func getTokenWithPKCE(ctx context.Context, cfg oauth2cli.Config) (*oauth2.Token, error) {
+	pkce, err := oauth2params.NewPKCE()
+	if err != nil {
+		return nil, fmt.Errorf("could not generate PKCE parameters: %w", err)
+	}
+	ready := make(chan string, 1)
+	cfg.AuthCodeOptions = pkce.AuthCodeOptions()
+	cfg.TokenRequestOptions = pkce.TokenRequestOptions()
+	cfg.LocalServerReadyChan = ready
+
+	var token *oauth2.Token
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.Go(func() error {
+		select {
+		case url := <-ready:
+			log.Printf("Open %s", url)
+			if err := browser.OpenURL(url); err != nil {
+				log.Printf("could not open the browser: %s", err)
+			}
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	eg.Go(func() error {
+		var err error
+		token, err = oauth2cli.GetToken(ctx, cfg)
+		return err
+	})
+	if err := eg.Wait(); err != nil {
+		return nil, fmt.Errorf("could not get a token: %w", err)
+	}
+	return token, nil
+}
+
+func tokenFile(name string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "oauth2cli-examples", name+".json")
+}
+
+func loadToken(name string) (*oauth2.Token, error) {
+	b, err := ioutil.ReadFile(tokenFile(name))
+	if err != nil {
+		return nil, err
+	}
+	var token oauth2.Token
+	if err := json.Unmarshal(b, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func saveToken(name string, token *oauth2.Token) error {
+	b, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(tokenFile(name)), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(tokenFile(name), b, 0600)
+}
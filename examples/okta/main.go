@@ -0,0 +1,48 @@
+// This is an example to get a token from Okta and call the userinfo API.
+//
+// Create a native app integration with the sign-in redirect URI http://localhost:8000,
+// and then run:
+//
+//	OKTA_DOMAIN=example.okta.com OKTA_CLIENT_ID=xxx go run ./examples/okta
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/int128/oauth2cli"
+	"github.com/int128/oauth2cli/examples/internal/example"
+	"golang.org/x/oauth2"
+)
+
+func main() {
+	domain, clientID := os.Getenv("OKTA_DOMAIN"), os.Getenv("OKTA_CLIENT_ID")
+	if domain == "" || clientID == "" {
+		log.Fatalf("You need to set OKTA_DOMAIN and OKTA_CLIENT_ID")
+	}
+	// use the default authorization server of the domain
+	issuer := "https://" + domain + "/oauth2/default"
+	cfg := oauth2cli.Config{
+		OAuth2Config: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: os.Getenv("OKTA_CLIENT_SECRET"),
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  issuer + "/v1/authorize",
+				TokenURL: issuer + "/v1/token",
+			},
+			Scopes: []string{"openid", "email", "profile", "offline_access"},
+		},
+		// Okta requires the exact redirect URI
+		LocalServerBindAddress: []string{"127.0.0.1:8000"},
+	}
+	ctx := context.Background()
+	// get a token with PKCE, or the cached one
+	token, err := example.GetToken(ctx, "okta", &cfg)
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+	if err := example.CallAPI(ctx, cfg, token, issuer+"/v1/userinfo"); err != nil {
+		log.Fatalf("%s", err)
+	}
+}
@@ -0,0 +1,29 @@
+package oauth2cli_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestExampleBuilds(t *testing.T) {
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skipf("go command not found: %s", err)
+	}
+	dir, err := ioutil.TempDir("", "oauth2cli-examples")
+	if err != nil {
+		t.Fatalf("could not create a temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, provider := range []string{"google", "github", "okta"} {
+		t.Run(provider, func(t *testing.T) {
+			cmd := exec.Command(goCmd, "build", "-o", filepath.Join(dir, provider), "./examples/"+provider)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("could not build the example: %s\n%s", err, out)
+			}
+		})
+	}
+}
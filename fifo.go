@@ -0,0 +1,90 @@
+package oauth2cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// receiveCodeViaFIFO reads the redirect URL from the named pipe of Config.LocalServerFIFOPath,
+// instead of the local server.
+func receiveCodeViaFIFO(ctx context.Context, c *Config) (*authorizationResponse, error) {
+	f, err := openFIFO(c.LocalServerFIFOPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not open the named pipe: %w", err)
+	}
+	defer os.Remove(c.LocalServerFIFOPath)
+	defer f.Close()
+
+	authCodeURL, err := prepareAuthorizationURL(c, fifoAddr(c.LocalServerFIFOPath))
+	if err != nil {
+		return nil, err
+	}
+	if c.LocalServerReadyChan != nil {
+		c.LocalServerReadyChan <- authCodeURL
+	}
+	if c.BrowserOpener != nil {
+		go func() {
+			if err := c.BrowserOpener.OpenURL(authCodeURL); err != nil {
+				c.logger().Warn("could not open the browser", "error", err)
+			}
+		}()
+	}
+
+	lineCh := make(chan string, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		line, err := bufio.NewReader(f).ReadString('\n')
+		if err != nil && line == "" {
+			errCh <- err
+			return
+		}
+		lineCh <- line
+	}()
+	select {
+	case line := <-lineCh:
+		return parseRedirectURL(c, strings.TrimSpace(line))
+	case err := <-errCh:
+		return nil, fmt.Errorf("could not read the named pipe: %w", err)
+	case <-ctx.Done():
+		return nil, fmt.Errorf("context done while waiting for authorization response: %w", ctx.Err())
+	}
+}
+
+// fifoAddr represents the path of the named pipe as net.Addr.
+type fifoAddr string
+
+func (a fifoAddr) Network() string { return "fifo" }
+func (a fifoAddr) String() string  { return string(a) }
+
+// parseRedirectURL returns the authorization response of the redirect URL.
+func parseRedirectURL(c *Config, redirectURL string) (*authorizationResponse, error) {
+	u, err := url.Parse(redirectURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redirect URL: %w", err)
+	}
+	q := u.Query()
+	if c.HybridResponseType != "" {
+		// the provider sends the response in the fragment, as well as hybridFragmentHTML
+		fragment, err := url.ParseQuery(u.Fragment)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fragment of the redirect URL: %w", err)
+		}
+		for k, v := range fragment {
+			if _, ok := q[k]; !ok {
+				q[k] = v
+			}
+		}
+	}
+	if errorCode := q.Get("error"); errorCode != "" {
+		return nil, fmt.Errorf("authorization error from server: %s %s", errorCode, q.Get("error_description"))
+	}
+	if err := c.validateAuthorizationResponse(q); err != nil {
+		return nil, err
+	}
+	c.stats.codeReceived()
+	return &authorizationResponse{code: q.Get("code"), idToken: q.Get("id_token")}, nil
+}
@@ -0,0 +1,14 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package oauth2cli
+
+import (
+	"errors"
+	"os"
+	"runtime"
+)
+
+func openFIFO(string) (*os.File, error) {
+	return nil, errors.New("named pipe is not supported on " + runtime.GOOS)
+}
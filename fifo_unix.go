@@ -0,0 +1,24 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package oauth2cli
+
+import (
+	"os"
+	"syscall"
+)
+
+// openFIFO creates a named pipe and opens it.
+// It is opened for read and write so that it does not block until a writer opens it,
+// and the read is unblocked when it is closed.
+func openFIFO(name string) (*os.File, error) {
+	if err := syscall.Mkfifo(name, 0600); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		_ = os.Remove(name)
+		return nil, err
+	}
+	return f, nil
+}
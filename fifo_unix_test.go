@@ -0,0 +1,176 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package oauth2cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestReceiveCodeViaFIFO(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+	defer cancel()
+	name := filepath.Join(t.TempDir(), "redirect")
+	readyCh := make(chan string, 1)
+	cfg := Config{
+		State:                "STATE",
+		LocalServerFIFOPath:  name,
+		LocalServerReadyChan: readyCh,
+	}
+	cfg.OAuth2Config.RedirectURL = "http://localhost:8000"
+	go func() {
+		<-readyCh
+		f, err := os.OpenFile(name, os.O_WRONLY, 0)
+		if err != nil {
+			t.Errorf("could not open the named pipe: %s", err)
+			return
+		}
+		defer f.Close()
+		if _, err := f.WriteString("http://localhost:8000/?state=STATE&code=AUTH_CODE\n"); err != nil {
+			t.Errorf("could not write the named pipe: %s", err)
+		}
+	}()
+	resp, err := receiveCodeViaFIFO(ctx, &cfg)
+	if err != nil {
+		t.Fatalf("receiveCodeViaFIFO error: %s", err)
+	}
+	if w := "AUTH_CODE"; resp.code != w {
+		t.Errorf("code wants %s but was %s", w, resp.code)
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("named pipe wants to be removed but was %v", err)
+	}
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		cancel()
+		cfg := Config{State: "STATE", LocalServerFIFOPath: filepath.Join(t.TempDir(), "redirect")}
+		if _, err := receiveCodeViaFIFO(ctx, &cfg); err == nil {
+			t.Errorf("receiveCodeViaFIFO wants error but was nil")
+		}
+	})
+	t.Run("SignerAndValidator", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+		defer cancel()
+		name := filepath.Join(t.TempDir(), "redirect")
+		readyCh := make(chan string, 1)
+		var started net.Addr
+		var validated *url.URL
+		cfg := Config{
+			State:                "STATE",
+			LocalServerFIFOPath:  name,
+			LocalServerReadyChan: readyCh,
+			LocalServerOnStarted: func(addr net.Addr) { started = addr },
+			AuthorizationURLValidator: func(u *url.URL) error {
+				validated = u
+				return nil
+			},
+			authCodeOptionsSigner: func(url.Values) ([]oauth2.AuthCodeOption, error) {
+				return []oauth2.AuthCodeOption{oauth2.SetAuthURLParam("request", "SIGNED")}, nil
+			},
+		}
+		cfg.OAuth2Config.RedirectURL = "http://localhost:8000"
+		go func() {
+			<-readyCh
+			f, err := os.OpenFile(name, os.O_WRONLY, 0)
+			if err != nil {
+				t.Errorf("could not open the named pipe: %s", err)
+				return
+			}
+			defer f.Close()
+			if _, err := f.WriteString("http://localhost:8000/?state=STATE&code=AUTH_CODE\n"); err != nil {
+				t.Errorf("could not write the named pipe: %s", err)
+			}
+		}()
+		if _, err := receiveCodeViaFIFO(ctx, &cfg); err != nil {
+			t.Fatalf("receiveCodeViaFIFO error: %s", err)
+		}
+		if started == nil || started.String() != name {
+			t.Errorf("LocalServerOnStarted wants %s but was %v", name, started)
+		}
+		if validated == nil {
+			t.Fatalf("AuthorizationURLValidator was not called")
+		}
+		if w := "SIGNED"; validated.Query().Get("request") != w {
+			t.Errorf("request wants %s but was %s", w, validated.Query().Get("request"))
+		}
+	})
+	t.Run("ValidatorError", func(t *testing.T) {
+		cfg := Config{
+			State:                     "STATE",
+			LocalServerFIFOPath:       filepath.Join(t.TempDir(), "redirect"),
+			AuthorizationURLValidator: func(*url.URL) error { return errors.New("rejected") },
+		}
+		cfg.OAuth2Config.RedirectURL = "http://localhost:8000"
+		if _, err := receiveCodeViaFIFO(context.TODO(), &cfg); err == nil {
+			t.Errorf("receiveCodeViaFIFO wants error but was nil")
+		}
+	})
+	t.Run("SessionStateValidatorError", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+		defer cancel()
+		name := filepath.Join(t.TempDir(), "redirect")
+		readyCh := make(chan string, 1)
+		cfg := Config{
+			State:                "STATE",
+			LocalServerFIFOPath:  name,
+			LocalServerReadyChan: readyCh,
+			SessionStateValidator: func(sessionState string) error {
+				return fmt.Errorf("unknown session %s", sessionState)
+			},
+		}
+		cfg.OAuth2Config.RedirectURL = "http://localhost:8000"
+		go func() {
+			<-readyCh
+			f, err := os.OpenFile(name, os.O_WRONLY, 0)
+			if err != nil {
+				t.Errorf("could not open the named pipe: %s", err)
+				return
+			}
+			defer f.Close()
+			if _, err := f.WriteString("http://localhost:8000/?state=STATE&code=AUTH_CODE&session_state=SESSION_STATE\n"); err != nil {
+				t.Errorf("could not write the named pipe: %s", err)
+			}
+		}()
+		_, err := receiveCodeViaFIFO(ctx, &cfg)
+		if err == nil {
+			t.Fatalf("receiveCodeViaFIFO wants error but was nil")
+		}
+		if w := "invalid session_state: unknown session SESSION_STATE"; err.Error() != w {
+			t.Errorf("error wants %s but was %s", w, err)
+		}
+	})
+	t.Run("StateMaxLength", func(t *testing.T) {
+		cfg := Config{State: strings.Repeat("x", 9), StateMaxLength: 8}
+		if _, err := parseRedirectURL(&cfg, "http://localhost:8000/?state="+cfg.State+"&code=AUTH_CODE"); err == nil {
+			t.Errorf("parseRedirectURL wants error but was nil")
+		}
+	})
+	t.Run("HybridFragment", func(t *testing.T) {
+		cfg := Config{State: "STATE", HybridResponseType: "code id_token"}
+		resp, err := parseRedirectURL(&cfg, "http://localhost:8000/#state=STATE&code=AUTH_CODE&id_token=ID_TOKEN")
+		if err != nil {
+			t.Fatalf("parseRedirectURL error: %s", err)
+		}
+		if resp.code != "AUTH_CODE" || resp.idToken != "ID_TOKEN" {
+			t.Errorf("response wants AUTH_CODE and ID_TOKEN but was %+v", resp)
+		}
+	})
+	t.Run("StateMismatch", func(t *testing.T) {
+		cfg := Config{State: "STATE"}
+		if _, err := parseRedirectURL(&cfg, "http://localhost:8000/?state=WRONG&code=AUTH_CODE"); err == nil {
+			t.Errorf("parseRedirectURL wants error but was nil")
+		}
+	})
+}
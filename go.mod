@@ -8,4 +8,9 @@ require (
 	golang.org/x/sync v0.0.0-20200317015054-43a5402ce75a
 )
 
-go 1.13
+require (
+	cloud.google.com/go v0.34.0 // indirect
+	golang.org/x/net v0.0.0-20190108225652-1e06a53dbb7e // indirect
+)
+
+go 1.21
@@ -0,0 +1,24 @@
+package oauth2cli
+
+import (
+	"context"
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+// NewAuthenticatedHTTPClient returns an HTTP client which sets the Authorization header of each request
+// to a token of the source, such as a CachedTokenSource.
+//
+// It is based on the HTTP client in the context (oauth2.HTTPClient) or http.DefaultClient,
+// i.e. the transport, timeout, redirect policy and cookie jar are inherited.
+// Unlike oauth2.NewClient, the source is called on each request, because it is expected to cache the token.
+func NewAuthenticatedHTTPClient(ctx context.Context, tokenSource oauth2.TokenSource) *http.Client {
+	base := contextClient(ctx)
+	return &http.Client{
+		Transport:     &oauth2.Transport{Source: tokenSource, Base: base.Transport},
+		CheckRedirect: base.CheckRedirect,
+		Jar:           base.Jar,
+		Timeout:       base.Timeout,
+	}
+}
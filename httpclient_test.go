@@ -0,0 +1,31 @@
+package oauth2cli
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestNewAuthenticatedHTTPClient(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if w := "Bearer ACCESS_TOKEN"; r.Header.Get("Authorization") != w {
+			t.Errorf("Authorization wants %s but was %s", w, r.Header.Get("Authorization"))
+		}
+	}))
+	defer s.Close()
+	ctx := context.WithValue(context.TODO(), oauth2.HTTPClient, &http.Client{Timeout: 3 * time.Second})
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "ACCESS_TOKEN", TokenType: "Bearer"})
+	client := NewAuthenticatedHTTPClient(ctx, ts)
+	if client.Timeout != 3*time.Second {
+		t.Errorf("Timeout wants 3s but was %s", client.Timeout)
+	}
+	resp, err := client.Get(s.URL)
+	if err != nil {
+		t.Fatalf("could not send a request: %s", err)
+	}
+	resp.Body.Close()
+}
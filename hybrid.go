@@ -0,0 +1,79 @@
+package oauth2cli
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+)
+
+// hybridFragmentHTML is the page of the local server if Config.HybridResponseType is set.
+// The provider sends the authorization response in the URL fragment, which the browser does not send to the server.
+// This page posts the parameters in the fragment to the local server,
+// or redirects to the authorization URL if the fragment has no response.
+// If Config.AuthorizationTimeout is set, it shows the remaining time before the redirect.
+var hybridFragmentHTML = template.Must(template.New("hybrid").Parse(`<html><body><script>
+var params = new URLSearchParams(location.hash.substring(1));
+var remaining = {{.Remaining}};
+if (params.has("code") || params.has("error")) {
+  var form = document.createElement("form");
+  form.method = "POST";
+  form.action = location.pathname;
+  params.forEach(function (value, key) {
+    var input = document.createElement("input");
+    input.type = "hidden";
+    input.name = key;
+    input.value = value;
+    form.appendChild(input);
+  });
+  document.body.appendChild(form);
+  form.submit();
+} else if (remaining) {
+  document.body.appendChild(document.createTextNode("Please authorize within " + remaining + ". Redirecting to the authorization page..."));
+  setTimeout(function () { location.replace({{.AuthCodeURL}}); }, 2000);
+} else {
+  location.replace({{.AuthCodeURL}});
+}
+</script></body></html>`))
+
+func (h *localServerHandler) handleHybridIndex(w http.ResponseWriter, r *http.Request) {
+	h.config.stats.browserOpened()
+	authCodeURL := h.config.OAuth2Config.AuthCodeURL(h.config.State, h.config.AuthCodeOptions...)
+	var remaining string
+	if !h.config.authorizationDeadline.IsZero() {
+		remaining, _ = h.authorizationRemaining()
+	}
+	var b bytes.Buffer
+	data := struct {
+		AuthCodeURL string
+		Remaining   string
+	}{authCodeURL, remaining}
+	if err := hybridFragmentHTML.Execute(&b, data); err != nil {
+		h.config.requestLogger(r).Error("could not render the fragment page", "error", err)
+		http.Error(w, "server error", 500)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	_, _ = w.Write(b.Bytes())
+}
+
+// handleHybridPost handles the authorization response posted by hybridFragmentHTML,
+// as the same as the redirect with the query parameters.
+func (h *localServerHandler) handleHybridPost(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			h.config.requestLogger(r).Warn("request body exceeds the limit",
+				"contentLength", r.ContentLength, "limit", maxBytesErr.Limit, "remoteAddr", r.RemoteAddr)
+			http.Error(w, "payload too large", 413)
+			return
+		}
+		h.config.requestLogger(r).Warn("invalid form of the authorization response",
+			"error", err, "remoteAddr", r.RemoteAddr)
+		http.Error(w, "bad request", 400)
+		return
+	}
+	redirect := r.Clone(r.Context())
+	redirect.URL.RawQuery = r.PostForm.Encode()
+	h.redirectHandler().ServeHTTP(w, redirect)
+}
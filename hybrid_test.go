@@ -0,0 +1,103 @@
+package oauth2cli
+
+import (
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestLocalServerHandler_Hybrid(t *testing.T) {
+	cfg := Config{
+		OAuth2Config:       oauth2.Config{ClientID: "YOUR_CLIENT_ID", Endpoint: oauth2.Endpoint{AuthURL: "https://example.com/auth"}},
+		State:              "STATE",
+		HybridResponseType: "code id_token",
+	}
+	if err := cfg.validateAndSetDefaults(); err != nil {
+		t.Fatalf("validateAndSetDefaults error: %s", err)
+	}
+	u, err := url.Parse(cfg.OAuth2Config.AuthCodeURL(cfg.State, cfg.AuthCodeOptions...))
+	if err != nil {
+		t.Fatalf("invalid authorization URL: %s", err)
+	}
+	if w := "code id_token"; u.Query().Get("response_type") != w {
+		t.Errorf("response_type wants %s but was %s", w, u.Query().Get("response_type"))
+	}
+
+	t.Run("FragmentPage", func(t *testing.T) {
+		h := &localServerHandler{config: &cfg}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != 200 {
+			t.Errorf("status wants 200 but was %d", w.Code)
+		}
+		if body := w.Body.String(); !strings.Contains(body, "location.hash") || !strings.Contains(body, "https://example.com/auth") {
+			t.Errorf("body wants the fragment page but was %s", body)
+		}
+	})
+
+	t.Run("FragmentPageAuthorizationTimeout", func(t *testing.T) {
+		timeoutCfg := cfg
+		timeoutCfg.authorizationDeadline = time.Now().Add(5 * time.Minute)
+		h := &localServerHandler{config: &timeoutCfg}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		body := w.Body.String()
+		for _, want := range []string{`var remaining = "4:59";`, "setTimeout", "https://example.com/auth"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("body wants %s but was %s", want, body)
+			}
+		}
+	})
+
+	t.Run("Post", func(t *testing.T) {
+		respCh := make(chan *authorizationResponse, 1)
+		h := &localServerHandler{config: &cfg, responseCh: respCh}
+		form := url.Values{"code": {"AUTH_CODE"}, "state": {"STATE"}, "id_token": {"ID_TOKEN"}}
+		r := httptest.NewRequest("POST", "/", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != 200 {
+			t.Errorf("status wants 200 but was %d", w.Code)
+		}
+		resp := <-respCh
+		if resp.err != nil {
+			t.Fatalf("response error: %s", resp.err)
+		}
+		if resp.code != "AUTH_CODE" || resp.idToken != "ID_TOKEN" {
+			t.Errorf("response wants AUTH_CODE and ID_TOKEN but was %+v", resp)
+		}
+	})
+
+	t.Run("PostExceedsLimit", func(t *testing.T) {
+		limitCfg := cfg
+		limitCfg.LocalServerResponseBodyLimit = 16
+		h := &localServerHandler{config: &limitCfg, responseCh: make(chan *authorizationResponse, 1)}
+		form := url.Values{"code": {"AUTH_CODE"}, "state": {"STATE"}, "id_token": {strings.Repeat("x", 64)}}
+		// a chunked body has no Content-Length, so the limit is enforced while reading
+		r := httptest.NewRequest("POST", "/", io.MultiReader(strings.NewReader(form.Encode())))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if r.ContentLength != -1 {
+			t.Fatalf("ContentLength wants -1 but was %d", r.ContentLength)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != 413 {
+			t.Errorf("status wants 413 but was %d", w.Code)
+		}
+	})
+
+	t.Run("PostWithoutHybrid", func(t *testing.T) {
+		h := &localServerHandler{config: &Config{State: "STATE"}}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader("code=AUTH_CODE&state=STATE")))
+		if w.Code != 404 {
+			t.Errorf("status wants 404 but was %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,188 @@
+package jose
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+)
+
+// JSONWebKey represents a public key in a JWK Set.
+// See https://tools.ietf.org/html/rfc7517
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// JSONWebKeySet represents a JWK Set.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// FetchJWKS fetches the JWK Set from the URL.
+func FetchJWKS(ctx context.Context, client *http.Client, jwksURL string) (*JSONWebKeySet, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", jwksURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create a request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not send a request: %w", err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the response body: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("%s returned status %d", jwksURL, resp.StatusCode)
+	}
+	var jwks JSONWebKeySet
+	if err := json.Unmarshal(b, &jwks); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+	return &jwks, nil
+}
+
+// Verify verifies the signature of the JWT by the key set and returns the claims.
+// It supports RS*, PS* and ES* algorithms.
+func Verify(s string, jwks *JSONWebKeySet) (map[string]interface{}, error) {
+	jwt, err := Decode(s)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(s, ".")
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+	if !strings.HasPrefix(jwt.Header.Alg, "RS") && !strings.HasPrefix(jwt.Header.Alg, "PS") && !strings.HasPrefix(jwt.Header.Alg, "ES") {
+		return nil, fmt.Errorf("unsupported algorithm %s", jwt.Header.Alg)
+	}
+	h, err := HashForAlg(jwt.Header.Alg)
+	if err != nil {
+		return nil, err
+	}
+	hasher := h.New()
+	_, _ = hasher.Write([]byte(parts[0] + "." + parts[1]))
+	digest := hasher.Sum(nil)
+
+	var errs []string
+	for _, key := range jwks.Keys {
+		if !key.matches(jwt.Header) {
+			continue
+		}
+		if err := key.verify(jwt.Header.Alg, h, digest, signature); err != nil {
+			errs = append(errs, fmt.Sprintf("kid=%s: %s", key.Kid, err))
+			continue
+		}
+		return jwt.Claims, nil
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no key found for kid=%s alg=%s", jwt.Header.Kid, jwt.Header.Alg)
+	}
+	return nil, fmt.Errorf("invalid signature: %s", strings.Join(errs, ", "))
+}
+
+func (k *JSONWebKey) matches(header Header) bool {
+	if k.Use != "" && k.Use != "sig" {
+		return false
+	}
+	if header.Kid != "" && k.Kid != header.Kid {
+		return false
+	}
+	if k.Alg != "" && k.Alg != header.Alg {
+		return false
+	}
+	switch header.Alg[0] {
+	case 'R', 'P':
+		return k.Kty == "RSA"
+	case 'E':
+		return k.Kty == "EC"
+	}
+	return false
+}
+
+func (k *JSONWebKey) verify(alg string, h crypto.Hash, digest, signature []byte) error {
+	switch k.Kty {
+	case "RSA":
+		pub, err := k.rsaPublicKey()
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(alg, "PS") {
+			return rsa.VerifyPSS(pub, h, digest, signature, nil)
+		}
+		return rsa.VerifyPKCS1v15(pub, h, digest, signature)
+	case "EC":
+		pub, err := k.ecdsaPublicKey()
+		if err != nil {
+			return err
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("signature wants %d bytes but was %d bytes", 2*size, len(signature))
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New("ecdsa verification error")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+func (k *JSONWebKey) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid n: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid e: %w", err)
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+}
+
+func (k *JSONWebKey) ecdsaPublicKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch k.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x: %w", err)
+	}
+	y, err := base64.RawURLEncoding.DecodeString(k.Y)
+	if err != nil {
+		return nil, fmt.Errorf("invalid y: %w", err)
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+}
@@ -0,0 +1,64 @@
+package jose
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate a key: %s", err)
+	}
+	jwks := &JSONWebKeySet{Keys: []JSONWebKey{{
+		Kty: "EC",
+		Kid: "EC_KEY",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(padBytes(ecKey.X, 32)),
+		Y:   base64.RawURLEncoding.EncodeToString(padBytes(ecKey.Y, 32)),
+	}}}
+	signingInput := encodeJSONPart(t, map[string]string{"alg": "ES256", "kid": "EC_KEY"}) + "." +
+		encodeJSONPart(t, map[string]string{"sub": "SUBJECT"})
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+	if err != nil {
+		t.Fatalf("could not sign: %s", err)
+	}
+	signature := append(padBytes(r, 32), padBytes(s, 32)...)
+
+	t.Run("ES256", func(t *testing.T) {
+		claims, err := Verify(signingInput+"."+base64.RawURLEncoding.EncodeToString(signature), jwks)
+		if err != nil {
+			t.Fatalf("Verify error: %s", err)
+		}
+		if w := "SUBJECT"; claims["sub"] != w {
+			t.Errorf("sub wants %s but was %v", w, claims["sub"])
+		}
+	})
+
+	t.Run("InvalidSignature", func(t *testing.T) {
+		signature[0] ^= 0xff
+		if _, err := Verify(signingInput+"."+base64.RawURLEncoding.EncodeToString(signature), jwks); err == nil {
+			t.Errorf("Verify wants error but was nil")
+		}
+	})
+}
+
+func encodeJSONPart(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("could not encode json: %s", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func padBytes(n *big.Int, size int) []byte {
+	b := n.Bytes()
+	return append(make([]byte, size-len(b)), b...)
+}
@@ -0,0 +1,35 @@
+package oauth2cli
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/int128/oauth2cli/internal/jose"
+)
+
+// validateCHash verifies the c_hash claim of the ID token against the authorization code.
+// See https://openid.net/specs/openid-connect-core-1_0.html#HybridIDToken
+func validateCHash(idToken, code string) error {
+	jwt, err := jose.Decode(idToken)
+	if err != nil {
+		return fmt.Errorf("invalid id_token: %w", err)
+	}
+	cHash, ok := jwt.Claims["c_hash"].(string)
+	if !ok || cHash == "" {
+		return errors.New("c_hash is missing in the id_token")
+	}
+	h, err := jose.HashForAlg(jwt.Header.Alg)
+	if err != nil {
+		return fmt.Errorf("could not compute c_hash: %w", err)
+	}
+	hash := h.New()
+	_, _ = hash.Write([]byte(code))
+	sum := hash.Sum(nil)
+	want := base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+	if subtle.ConstantTimeCompare([]byte(want), []byte(cHash)) != 1 {
+		return fmt.Errorf("c_hash does not match (wants %s but got %s)", want, cHash)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package oauth2cli
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func newTestJWT(header, claims string) string {
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(header)) + "." + enc.EncodeToString([]byte(claims)) + ".SIGNATURE"
+}
+
+func Test_validateCHash(t *testing.T) {
+	// Testdata described at:
+	// https://openid.net/specs/openid-connect-core-1_0.html#code-id_tokenExample
+	const code = "Qcb0Orv1zh30vL1MPRsbm-diHiMwcLyZvn1arpZv-Jxf_11jnpEX3Tgfvk"
+
+	t.Run("Match", func(t *testing.T) {
+		idToken := newTestJWT(`{"alg":"RS256"}`, `{"c_hash":"LDktKdoQak3Pk0cnXxCltA"}`)
+		if err := validateCHash(idToken, code); err != nil {
+			t.Errorf("validateCHash error: %s", err)
+		}
+	})
+	t.Run("Mismatch", func(t *testing.T) {
+		idToken := newTestJWT(`{"alg":"RS256"}`, `{"c_hash":"LDktKdoQak3Pk0cnXxCltA"}`)
+		if err := validateCHash(idToken, "ANOTHER_CODE"); err == nil {
+			t.Errorf("validateCHash wants error but was nil")
+		}
+	})
+	t.Run("Missing", func(t *testing.T) {
+		idToken := newTestJWT(`{"alg":"RS256"}`, `{}`)
+		if err := validateCHash(idToken, code); err == nil {
+			t.Errorf("validateCHash wants error but was nil")
+		}
+	})
+	t.Run("UnsupportedAlgorithm", func(t *testing.T) {
+		idToken := newTestJWT(`{"alg":"none"}`, `{"c_hash":"LDktKdoQak3Pk0cnXxCltA"}`)
+		if err := validateCHash(idToken, code); err == nil {
+			t.Errorf("validateCHash wants error but was nil")
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package oauth2cli
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// keepAliveListener is a listener which sets the TCP keep-alive period of each connection.
+// listener.Listener does not accept a net.ListenConfig, so this sets it on the accepted connections.
+type keepAliveListener struct {
+	net.Listener
+	period time.Duration
+}
+
+func (l *keepAliveListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	setTCPKeepAlive(conn, l.period)
+	return conn, nil
+}
+
+// setTCPKeepAlive sets the keep-alive period of the TCP connection.
+// If the period is negative, this disables keep-alive.
+func setTCPKeepAlive(conn net.Conn, period time.Duration) {
+	tc, ok := conn.(*net.TCPConn)
+	if !ok {
+		return
+	}
+	if period < 0 {
+		_ = tc.SetKeepAlive(false)
+		return
+	}
+	_ = tc.SetKeepAlive(true)
+	_ = tc.SetKeepAlivePeriod(period)
+}
+
+// withTokenEndpointKeepAlive returns a context which has an HTTP client
+// with the TCP keep-alive period of Config.TokenEndpointKeepAlive.
+// The other settings of the HTTP client in the context are inherited,
+// including the dialer of the transport.
+// The caller must call the returned function after the request,
+// to close the idle connections of the cloned transport.
+func withTokenEndpointKeepAlive(ctx context.Context, c *Config) (context.Context, func()) {
+	if c.TokenEndpointKeepAlive == 0 {
+		return ctx, func() {}
+	}
+	base := contextClient(ctx)
+	var transport *http.Transport
+	switch t := base.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		// a custom transport cannot be configured
+		return ctx, func() {}
+	}
+	dial := transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 30 * time.Second}).DialContext
+	}
+	period := c.TokenEndpointKeepAlive
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		setTCPKeepAlive(conn, period)
+		return conn, nil
+	}
+	client := *base
+	client.Transport = transport
+	return context.WithValue(ctx, oauth2.HTTPClient, &client), transport.CloseIdleConnections
+}
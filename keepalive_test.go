@@ -0,0 +1,93 @@
+package oauth2cli
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestKeepAliveListener(t *testing.T) {
+	for _, period := range []time.Duration{time.Minute, -1} {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("could not listen: %s", err)
+		}
+		kl := &keepAliveListener{Listener: l, period: period}
+		go func() {
+			conn, err := net.Dial("tcp", l.Addr().String())
+			if err == nil {
+				_ = conn.Close()
+			}
+		}()
+		conn, err := kl.Accept()
+		if err != nil {
+			t.Fatalf("Accept error: %s", err)
+		}
+		_ = conn.Close()
+		_ = kl.Close()
+	}
+}
+
+func TestWithTokenEndpointKeepAlive(t *testing.T) {
+	t.Run("Disabled", func(t *testing.T) {
+		ctx := context.TODO()
+		if got, _ := withTokenEndpointKeepAlive(ctx, &Config{}); got != ctx {
+			t.Errorf("context wants to be unchanged")
+		}
+	})
+	t.Run("DefaultTransport", func(t *testing.T) {
+		base := &http.Client{Timeout: 3 * time.Second}
+		ctx := context.WithValue(context.TODO(), oauth2.HTTPClient, base)
+		got, closeIdleConnections := withTokenEndpointKeepAlive(ctx, &Config{TokenEndpointKeepAlive: time.Minute})
+		defer closeIdleConnections()
+		client := contextClient(got)
+		if client == base {
+			t.Fatalf("client wants to be replaced")
+		}
+		if _, ok := client.Transport.(*http.Transport); !ok {
+			t.Errorf("Transport wants *http.Transport but was %T", client.Transport)
+		}
+		if client.Timeout != 3*time.Second {
+			t.Errorf("Timeout wants 3s but was %s", client.Timeout)
+		}
+	})
+	t.Run("CustomDialContext", func(t *testing.T) {
+		s := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+		defer s.Close()
+		var dialed int
+		dialer := &net.Dialer{}
+		base := &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				dialed++
+				return dialer.DialContext(ctx, network, addr)
+			},
+		}}
+		ctx := context.WithValue(context.TODO(), oauth2.HTTPClient, base)
+		got, closeIdleConnections := withTokenEndpointKeepAlive(ctx, &Config{TokenEndpointKeepAlive: time.Minute})
+		resp, err := contextClient(got).Get(s.URL)
+		if err != nil {
+			t.Fatalf("could not send a request: %s", err)
+		}
+		resp.Body.Close()
+		closeIdleConnections()
+		if dialed != 1 {
+			t.Errorf("DialContext of the caller wants to be called once but was %d", dialed)
+		}
+	})
+	t.Run("CustomTransport", func(t *testing.T) {
+		base := &http.Client{Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) { return nil, nil })}
+		ctx := context.WithValue(context.TODO(), oauth2.HTTPClient, base)
+		if got, _ := withTokenEndpointKeepAlive(ctx, &Config{TokenEndpointKeepAlive: time.Minute}); got != ctx {
+			t.Errorf("context wants to be unchanged")
+		}
+	})
+}
@@ -0,0 +1,32 @@
+package oauth2cli
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+)
+
+func (c *Config) logger() *slog.Logger {
+	if c.Logger == nil {
+		return discardLogger
+	}
+	return c.Logger
+}
+
+// requestLogger returns the logger with the request ID if it is set by NewRequestIDMiddleware.
+func (c *Config) requestLogger(r *http.Request) *slog.Logger {
+	if id := RequestIDFromContext(r.Context()); id != "" {
+		return c.logger().With("requestID", id)
+	}
+	return c.logger()
+}
+
+var discardLogger = slog.New(discardHandler{})
+
+// discardHandler is a slog.Handler which discards all records.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
@@ -0,0 +1,136 @@
+// Package logging provides loggers for the debug output of oauth2cli.
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// NewFileRotatingLogger returns a logger which writes the records of all levels to the file in JSON.
+// The file is rotated when it exceeds maxSizeMB megabytes,
+// and at most maxBackups old files are kept as path.1, path.2 and so on.
+//
+// You can set it to oauth2cli.Config.Logger to keep the debug output out of stdout and stderr.
+// The caller must call the returned function to close the file.
+func NewFileRotatingLogger(path string, maxSizeMB int, maxBackups int) (*slog.Logger, func() error, error) {
+	if maxSizeMB <= 0 {
+		return nil, nil, errors.New("maxSizeMB must be positive")
+	}
+	w, err := NewRotatingWriter(path, int64(maxSizeMB)<<20, maxBackups)
+	if err != nil {
+		return nil, nil, err
+	}
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug})), w.Close, nil
+}
+
+// RotatingWriter is a writer to a file which is rotated at the size.
+// It is safe for concurrent use.
+// If the file could not be opened on rotation, it retries on the next write.
+type RotatingWriter struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu     sync.Mutex
+	file   *os.File // nil if closed or the rotation failed
+	size   int64
+	closed bool
+}
+
+// NewRotatingWriter opens the file for appending.
+// The file is rotated when it exceeds maxSize bytes, and at most maxBackups old files are kept.
+func NewRotatingWriter(path string, maxSize int64, maxBackups int) (*RotatingWriter, error) {
+	if maxSize <= 0 {
+		return nil, errors.New("maxSize must be positive")
+	}
+	if maxBackups < 0 {
+		return nil, errors.New("maxBackups must not be negative")
+	}
+	w := &RotatingWriter{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RotatingWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("could not open the log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("could not stat the log file: %w", err)
+	}
+	w.file, w.size = f, info.Size()
+	return nil
+}
+
+// Write writes the bytes to the file.
+// The file is rotated before writing if it would exceed the size.
+func (w *RotatingWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.file == nil {
+		// the previous rotation failed
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	if w.size > 0 && w.size+int64(len(b)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *RotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("could not close the log file: %w", err)
+	}
+	w.file = nil
+	if w.maxBackups == 0 {
+		if err := os.Remove(w.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not remove the log file: %w", err)
+		}
+		return w.open()
+	}
+	// shift path.N-1 to path.N, ..., path to path.1
+	_ = os.Remove(backupPath(w.path, w.maxBackups))
+	for i := w.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(backupPath(w.path, i), backupPath(w.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("could not rotate the log file: %w", err)
+		}
+	}
+	if err := os.Rename(w.path, backupPath(w.path, 1)); err != nil {
+		return fmt.Errorf("could not rotate the log file: %w", err)
+	}
+	return w.open()
+}
+
+func backupPath(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
+
+// Close closes the file.
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
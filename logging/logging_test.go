@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "debug.log")
+	w, err := NewRotatingWriter(path, 10, 2)
+	if err != nil {
+		t.Fatalf("NewRotatingWriter error: %s", err)
+	}
+	defer w.Close()
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Write error: %s", err)
+		}
+	}
+	for name, want := range map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	} {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("could not read %s: %s", name, err)
+		}
+		if string(b) != want {
+			t.Errorf("%s wants %q but was %q", filepath.Base(name), want, b)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("%s.3 wants not exist but was %v", filepath.Base(path), err)
+	}
+}
+
+func TestRotatingWriter_RotationError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "debug.log")
+	w, err := NewRotatingWriter(path, 10, 1)
+	if err != nil {
+		t.Fatalf("NewRotatingWriter error: %s", err)
+	}
+	defer w.Close()
+	if _, err := w.Write([]byte("first\n")); err != nil {
+		t.Fatalf("Write error: %s", err)
+	}
+	// a non-empty directory cannot be replaced by the rename
+	if err := os.MkdirAll(filepath.Join(path+".1", "dir"), 0700); err != nil {
+		t.Fatalf("could not create a directory: %s", err)
+	}
+	if _, err := w.Write([]byte("second\n")); err == nil {
+		t.Fatalf("Write wants error but was nil")
+	}
+	if err := os.RemoveAll(path + ".1"); err != nil {
+		t.Fatalf("could not remove the directory: %s", err)
+	}
+	if _, err := w.Write([]byte("third\n")); err != nil {
+		t.Fatalf("Write wants to recover but was %s", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read the log: %s", err)
+	}
+	if w := "third\n"; string(b) != w {
+		t.Errorf("log wants %q but was %q", w, b)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Errorf("Close error: %s", err)
+	}
+	if _, err := w.Write([]byte("fourth\n")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Write wants os.ErrClosed after Close but was %v", err)
+	}
+}
+
+func TestNewFileRotatingLogger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "debug.log")
+	logger, closeLogger, err := NewFileRotatingLogger(path, 1, 1)
+	if err != nil {
+		t.Fatalf("NewFileRotatingLogger error: %s", err)
+	}
+	logger.Debug("hello", "key", "value")
+	if err := closeLogger(); err != nil {
+		t.Errorf("close error: %s", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read the log: %s", err)
+	}
+	if !strings.Contains(string(b), `"msg":"hello","key":"value"`) {
+		t.Errorf("log wants the debug record but was %s", b)
+	}
+}
@@ -0,0 +1,77 @@
+package oauth2cli
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Names of NetTraceEvent.
+const (
+	NetTraceDNSDone              = "DNSDone"
+	NetTraceConnectDone          = "ConnectDone"
+	NetTraceTLSHandshakeDone     = "TLSHandshakeDone"
+	NetTraceGotFirstResponseByte = "GotFirstResponseByte"
+)
+
+// NetTrace represents the network events during the token exchange.
+type NetTrace struct {
+	// Time when the token exchange is started.
+	StartedAt time.Time
+	// Events in order of occurrence.
+	Events []NetTraceEvent
+}
+
+// NetTraceEvent represents a network event.
+type NetTraceEvent struct {
+	// Name of the event, such as NetTraceConnectDone.
+	Name string
+	Time time.Time
+	// Remote address if available.
+	Addr string
+	// Non-nil if the operation failed.
+	Err error
+}
+
+type netTraceRecorder struct {
+	mu    sync.Mutex
+	trace NetTrace
+}
+
+func newNetTraceRecorder() *netTraceRecorder {
+	return &netTraceRecorder{trace: NetTrace{StartedAt: time.Now()}}
+}
+
+func (r *netTraceRecorder) record(name, addr string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.trace.Events = append(r.trace.Events, NetTraceEvent{Name: name, Time: time.Now(), Addr: addr, Err: err})
+}
+
+// withClientTrace returns a context which records the network events.
+func (r *netTraceRecorder) withClientTrace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			r.record(NetTraceDNSDone, "", info.Err)
+		},
+		ConnectDone: func(network, addr string, err error) {
+			r.record(NetTraceConnectDone, addr, err)
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			r.record(NetTraceTLSHandshakeDone, "", err)
+		},
+		GotFirstResponseByte: func() {
+			r.record(NetTraceGotFirstResponseByte, "", nil)
+		},
+	})
+}
+
+func (r *netTraceRecorder) netTrace() *NetTrace {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.trace
+	t.Events = append([]NetTraceEvent(nil), r.trace.Events...)
+	return &t
+}
@@ -0,0 +1,40 @@
+package oauth2cli_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/int128/oauth2cli"
+	oauth2clitesting "github.com/int128/oauth2cli/testing"
+	"golang.org/x/oauth2"
+)
+
+func TestGetTokenWithResult_NetTrace(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+	defer cancel()
+	// use a new connection to record the connect event
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: &http.Transport{}})
+	cfg, _ := oauth2clitesting.GoogleTestConfig(t)
+	cfg.EnableNetTrace = true
+	result, err := oauth2cli.GetTokenWithResult(ctx, cfg)
+	if err != nil {
+		t.Fatalf("could not get a token: %s", err)
+	}
+	if result.NetTrace == nil {
+		t.Fatalf("NetTrace wants non-nil but was nil")
+	}
+	names := make(map[string]bool)
+	for _, e := range result.NetTrace.Events {
+		names[e.Name] = true
+		if e.Time.Before(result.NetTrace.StartedAt) {
+			t.Errorf("event %s occurred before the exchange started", e.Name)
+		}
+	}
+	for _, name := range []string{oauth2cli.NetTraceConnectDone, oauth2cli.NetTraceGotFirstResponseByte} {
+		if !names[name] {
+			t.Errorf("event %s wants to be recorded but was not: %+v", name, result.NetTrace.Events)
+		}
+	}
+}
@@ -4,18 +4,61 @@ package oauth2cli
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"html/template"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/int128/oauth2cli/oauth2params"
 	"golang.org/x/oauth2"
 )
 
+const defaultLocalServerResponseBodyLimit = 64 << 10
+
+const defaultTokenExpirySlack = 10 * time.Second
+
+const defaultTokenEndpointTimeout = 30 * time.Second
+
+const defaultLocalServerRequestTimeout = 30 * time.Second
+
 var noopMiddleware = func(h http.Handler) http.Handler { return h }
 
 // DefaultLocalServerSuccessHTML is a default response body on authorization success.
 const DefaultLocalServerSuccessHTML = `<html><body>OK<script>window.close()</script></body></html>`
 
+// DefaultLocalServerAlreadyUsedHTML is a default response body on a redirect after the authorization is completed.
+const DefaultLocalServerAlreadyUsedHTML = `<html><body>You have already authorized. Close this window.<script>window.close()</script></body></html>`
+
+// DefaultLocalServerTimeoutHTML is a default response body of the local server when Config.AuthorizationTimeout is set.
+// It is rendered by html/template with AuthCodeURL, Remaining (e.g. 4:59) and RemainingSeconds.
+// It counts down the remaining time from data-timeout by the script,
+// and then redirects to the authorization page by the meta refresh, which also works without the script.
+const DefaultLocalServerTimeoutHTML = `<html><head><meta http-equiv="refresh" content="2;url={{.AuthCodeURL}}"></head>` +
+	`<body data-timeout="{{.RemainingSeconds}}">` +
+	`<p>Please authorize in the browser. Time remaining: <span id="remaining">{{.Remaining}}</span>.</p>` +
+	`<p>Redirecting to the authorization page... <a href="{{.AuthCodeURL}}">Open the authorization page</a></p>` +
+	`<script>(function () {
+  var seconds = Number(document.body.getAttribute("data-timeout"));
+  var e = document.getElementById("remaining");
+  setInterval(function () {
+    seconds = Math.max(seconds - 1, 0);
+    e.textContent = Math.floor(seconds / 60) + ":" + ("0" + seconds % 60).slice(-2);
+  }, 1000);
+})();</script></body></html>`
+
+// DefaultLocalServerErrorHTML is a default response body on authorization error.
+// It is rendered by html/template with ErrorCode and ErrorDescription of the authorization response.
+const DefaultLocalServerErrorHTML = `<html><body>Authorization failed: {{.ErrorDescription}}<script>window.close()</script></body></html>`
+
 // Config represents a config for GetToken.
 type Config struct {
 	// OAuth2 config.
@@ -25,21 +68,161 @@ type Config struct {
 	// You can set this if your provider does not accept localhost.
 	// Default to localhost.
 	RedirectURLHostname string
+	// Scopes to request in addition to OAuth2Config.Scopes.
+	// They are appended to a copy of OAuth2Config.Scopes without duplicates,
+	// so you can add optional scopes to a given config. Default to none.
+	AdditionalScopes []string
 	// Options for an authorization request.
 	// You can set oauth2.AccessTypeOffline and the PKCE options here.
 	AuthCodeOptions []oauth2.AuthCodeOption
+	// Value of response_type in the authorization request,
+	// for a provider which requires a custom value such as "code token".
+	// It must contain "code", because the code is exchanged for a token.
+	// Default to code.
+	ResponseType string
+	// Fine-grained authorization of RFC 9396 Rich Authorization Requests.
+	// If set, the authorization request has authorization_details of the JSON.
+	// Use ExtractAuthorizationDetails to get the granted details in the token response.
+	// Default to none.
+	AuthorizationDetails []AuthorizationDetail
+	// If true, ResponseType is not validated. Default to false.
+	SkipResponseTypeValidation bool
+	// If true, verify that the PKCE method in AuthCodeOptions is one of PKCERegisteredMethods,
+	// for a provider which requires the methods to be registered per client.
+	// GetToken returns a ConfigurationError if not. Default to false.
+	PKCERegistrationEnabled bool
+	// PKCE methods registered to the provider, e.g. S256.
+	PKCERegisteredMethods []string
 	// Options for a token request.
 	// You can set the PKCE options here.
 	TokenRequestOptions []oauth2.AuthCodeOption
+	// Timeout of GetToken, i.e. the authorization in the browser and the token exchange.
+	// If set, the local server shows LocalServerTimeoutHTML with the remaining time
+	// before redirecting to the authorization page. Default to none.
+	AuthorizationTimeout time.Duration
+	// If true, GetToken is canceled on SIGINT or SIGTERM, e.g. Ctrl+C.
+	// The context of the caller is not canceled.
+	// A redirect in progress receives LocalServerErrorHTML of the cancellation
+	// before the local server is shut down. Default to false.
+	HandleOSSignals bool
+	// Timeout of the token request to exchange the code,
+	// regardless of the context and the HTTP client.
+	// Set a negative value to disable. Default to 30 seconds.
+	TokenEndpointTimeout time.Duration
+	// TCP keep-alive period of the connections to the token endpoint.
+	// This applies if the HTTP client in the context has no transport or an *http.Transport.
+	// Set a negative value to disable keep-alive. Default to 0, i.e. the default of Go (15 seconds).
+	TokenEndpointKeepAlive time.Duration
+	// If true, accept a token response signed as a JWT, i.e. Content-Type application/jwt,
+	// in addition to a JSON response.
+	// The signature is verified by TokenResponseJWKSURL and the claims are validated,
+	// and then the claims are parsed as the token response.
+	// If the verification fails, GetToken returns a TokenResponseJWTVerificationError. Default to false.
+	TokenResponseJWT bool
+	// URL of the JWK Set to verify the token response JWT.
+	// This is required if TokenResponseJWT is set.
+	TokenResponseJWKSURL string
+	// Issuer of the token response JWT, i.e. the iss claim.
+	// The aud claim must contain the client ID, and the exp claim must be in the future.
+	// This is required if TokenResponseJWT is set.
+	TokenResponseJWTIssuer string
+	// Logger for diagnostics of the flow. Default to none.
+	Logger *slog.Logger
+	// If true, log the headers of the redirect to the local server to Logger at the debug level,
+	// e.g. for security auditing of the user agent and referrer.
+	// The values of Authorization, Cookie and Proxy-Authorization are redacted. Default to false.
+	LocalServerVerboseHeaders bool
+	// If true, record the network events during the token exchange.
+	// You can get them from GetTokenResult.NetTrace.
+	EnableNetTrace bool
+
+	// A channel to receive the snapshots of GetTokenStats while GetToken is running.
+	// A snapshot is discarded if the channel is not ready to receive. Default to none.
+	StatsChan chan<- GetTokenStats
+	// Interval of the snapshots sent to StatsChan. Default to 1 second.
+	StatsInterval time.Duration
+
+	// A function called after CachedTokenSource refreshes the token successfully.
+	// You can log the refresh or save the new token to your storage. Default to none.
+	OnTokenRefreshed func(ctx context.Context, oldToken, newToken *oauth2.Token)
+	// A function called when CachedTokenSource could not refresh the token. Default to none.
+	OnTokenRefreshFailed func(ctx context.Context, token *oauth2.Token, err error)
+	// CachedTokenSource refreshes a token when it expires within this duration,
+	// to allow for clock skew and latency.
+	// Set a negative value to refresh only after the expiry. Default to 10 seconds.
+	TokenExpirySlack time.Duration
 	// State parameter in the authorization request.
-	// Default to a string of random 32 bytes.
+	// Default to a string of random bytes of StateLength.
 	State string
+	// Number of random bytes of the generated state.
+	// It is encoded in base64url without padding, i.e. 32 bytes becomes 43 characters.
+	// Default to 32.
+	StateLength int
+	// Maximum length of the state parameter, if the provider has a limit.
+	// If the state exceeds it, GetToken returns an error.
+	// Default to 0 (unlimited).
+	StateMaxLength int
+	// A function to validate the session_state parameter in the authorization response.
+	// This is called only if the response has session_state.
+	// If it returns an error, the authorization response is rejected.
+	// See https://openid.net/specs/openid-connect-session-1_0.html
+	SessionStateValidator func(sessionState string) error
+	// Deduplicator of GetToken calls with the same State.
+	// If set and another call with the same State is in progress,
+	// GetToken waits for it and returns its result instead of opening another authorization.
+	// If the other call has already finished, GetToken returns an error.
+	// This is useful only if you set State explicitly. Default to none.
+	SessionDeduplicator SessionDeduplicator
+	// Cache to save the authorization code when the flow is interrupted,
+	// i.e. the context is done after the code is received and before the exchange is completed.
+	// If set, the next GetToken call with the same client ID and scopes
+	// exchanges the saved code without opening the browser.
+	// The token request options (such as the PKCE verifier) must be the same as the interrupted call.
+	// Default to none.
+	InterruptionRecoveryCache CodeCache
+	// Lifetime of a saved code. Default to 1 minute.
+	InterruptionRecoveryTTL time.Duration
+	// A function called with the authorization code before it is exchanged,
+	// e.g. to audit or validate the code.
+	// This is called after the state validation, and also before a code in InterruptionRecoveryCache is exchanged.
+	// If it returns an error, the exchange is aborted and GetToken returns the error.
+	// Note that the code is a credential and should be treated as sensitive. Default to none.
+	ExchangeCodeHook func(ctx context.Context, code string) error
+	// If set, verify the endpoints of the provider before the local server is started.
+	// For example, set NewStandardPreflightCheck.
+	// If it returns an error, GetToken returns the error. Default to none.
+	PreflightOIDCCheck PreflightOIDCCheck
+	// A function to verify the authorization URL, e.g. the scheme, host or required parameters.
+	// This is called after the local server is started and before LocalServerReadyChan receives the URL,
+	// i.e. before the browser is opened.
+	// If it returns an error, GetToken returns the error. Default to none.
+	AuthorizationURLValidator func(u *url.URL) error
+	// Response type of the hybrid flow, e.g. "code id_token".
+	// If set, the authorization request has this response_type,
+	// and the local server receives the authorization response in the URL fragment by a script.
+	// The ID token in the response is available in GetTokenResult.AuthorizationResponseIDToken.
+	// This is useful for the migration from the implicit flow. Default to none.
+	// See https://openid.net/specs/openid-connect-core-1_0.html#HybridFlowAuth
+	HybridResponseType string
+	// If true, verify the c_hash claim of the ID token in the authorization response
+	// against the authorization code before exchanging it.
+	// This applies only if the authorization response has id_token, i.e. the hybrid flow.
+	// See https://openid.net/specs/openid-connect-core-1_0.html#HybridIDToken
+	ValidateCHash bool
 
 	// Candidates of hostname and port which the local server binds to.
 	// You can set port number to 0 to allocate a free port.
 	// If multiple addresses are given, it will try the ports in order.
 	// If nil or an empty slice is given, it defaults to "127.0.0.1:0" i.e. a free port.
 	LocalServerBindAddress []string
+	// Path of a named pipe to receive the redirect URL instead of the local server.
+	// If set, GetToken creates a named pipe at the path and reads a line of the redirect URL from it,
+	// e.g. sent by a browser automation tool. The local server is not started.
+	// LocalServerReadyChan and BrowserOpener receive the authorization URL instead of the local server.
+	// The authorization response is validated as well as the local server,
+	// and the parameters in the fragment are also read if HybridResponseType is set.
+	// OAuth2Config.RedirectURL is required. This is available only on Unix. Default to none.
+	LocalServerFIFOPath string
 
 	// A PEM-encoded certificate, and possibly the complete certificate chain.
 	// When set, the server will serve TLS traffic using the specified
@@ -49,14 +232,127 @@ type Config struct {
 	// A PEM-encoded private key for the certificate.
 	// This is required when LocalServerCertFile is set.
 	LocalServerKeyFile string
+	// Scheme of the redirect URL, "http" or "https".
+	// This is useful if a TLS proxy is in front of the local server.
+	// Default to https if LocalServerCertFile is set, otherwise http.
+	LocalServerScheme string
+	// A function to verify the client certificate presented by the browser.
+	// When set, the local server requests (but does not require) a client certificate,
+	// and calls this function with the certificate on the authorization response.
+	// The certificate is nil if the browser did not present one.
+	// If it returns an error, the request is rejected with 403.
+	// This requires LocalServerCertFile and LocalServerKeyFile.
+	LocalServerClientCertValidator func(cert *x509.Certificate) error
+
+	// If true, the local server reads the PROXY protocol (version 1 or 2) header of each connection,
+	// and the remote address of a request becomes the client address in the header.
+	// This is useful if the local server is behind a TCP proxy or load balancer.
+	// Every connection must have the header. Default to false.
+	LocalServerProxyProto bool
+
+	// TCP keep-alive period of the connections to the local server,
+	// e.g. to prevent a NAT device from dropping an idle connection while the user is authorizing.
+	// Set a negative value to disable keep-alive. Default to 0, i.e. the default of Go (15 seconds).
+	LocalServerTCPKeepAlive time.Duration
+
+	// Maximum duration to wait for a connection to the local server at a time.
+	// On each timeout, the local server checks whether the context is done and waits again.
+	// It logs the timeout to Logger at the debug level.
+	// Default to 0, i.e. wait until the context is done.
+	LocalServerAcceptTimeout time.Duration
+
+	// A tunnel to expose the local server to the internet.
+	// If set, the redirect URL is the public URL of the tunnel.
+	// This is useful if the browser runs on another machine. Default to none.
+	TunnelProvider TunnelProvider
+
+	// If true, the redirect URL has a random path of 16 bytes in hex, e.g. http://localhost:8000/a3f8c1b2...
+	// The local server responds 404 to the other paths,
+	// so that an attacker needs to guess both the port and the path to send a fake redirect.
+	// Your provider must accept any path of the redirect URL. Default to false.
+	LocalServerRandomizePath bool
 
 	// Response HTML body on authorization completed.
+	// If the request has Accept: application/json, e.g. fetch() of an IDE extension,
+	// the local server responds {"status":"ok","code":"..."} instead.
 	// Default to DefaultLocalServerSuccessHTML.
 	LocalServerSuccessHTML string
+	// Template of the response HTML body on authorization error.
+	// It is rendered by html/template with ErrorCode and ErrorDescription of the authorization response.
+	// Default to DefaultLocalServerErrorHTML.
+	LocalServerErrorHTML string
+	// Response HTML body on a redirect after the first authorization response,
+	// i.e. the single-use local server has already been used.
+	// It is sent with 410 Gone. This applies only if LocalServerSingleUse is true.
+	// Default to DefaultLocalServerAlreadyUsedHTML.
+	LocalServerAlreadyUsedHTML string
+	// Template of the response HTML body of the local server when AuthorizationTimeout is set.
+	// It is rendered by html/template with AuthCodeURL, Remaining and RemainingSeconds.
+	// It should redirect to AuthCodeURL, e.g. by the meta refresh, as well as the default.
+	// Default to DefaultLocalServerTimeoutHTML.
+	LocalServerTimeoutHTML string
+	// If true, remove the window.close() script from the success HTML, error HTML and already-used HTML.
+	// The user closes the browser tab manually. Default to false.
+	LocalServerSuppressWindowClose bool
+	// If true, the local server accepts only the first authorization response which passes the state validation.
+	// Subsequent redirects receive 410 Gone, so that a race of redirects cannot exchange a wrong code.
+	// Set false explicitly to accept repeated redirects. Default to true.
+	LocalServerSingleUse *bool
+	// If true, the local server responds the success page to a subsequent redirect with the same state,
+	// e.g. the authorization URL was opened in two browser tabs.
+	// The first code is used and the subsequent ones are ignored in either case.
+	// This applies only if LocalServerSingleUse is true.
+	// Default to false, i.e. they receive LocalServerAlreadyUsedHTML.
+	AllowDuplicateState bool
+	// Origins allowed to access the local server by CORS, e.g. https://app.example.com.
+	// This is useful if a web application in the browser sends the authorization response.
+	// "*" allows any origin. Default to none, i.e. no CORS headers.
+	LocalServerCORSOrigins []string
+	// If true, CORS requests may include credentials such as cookies.
+	// The local server responds Access-Control-Allow-Credentials and the specific origin.
+	// This requires LocalServerCORSOrigins without "*".
+	LocalServerCORSAllowCredentials bool
+	// Maximum size of a request body to the local server in bytes.
+	// A request with a larger body receives 413 Payload Too Large.
+	// Default to 64 KB.
+	LocalServerResponseBodyLimit int64
 	// Middleware for the local server. Default to none.
 	LocalServerMiddleware func(h http.Handler) http.Handler
+	// Timeout of each request to the local server including LocalServerMiddleware.
+	// It is set to the deadline of the request context, so a handler should return when the context is done.
+	// If a request exceeds it, the local server responds 503 and continues to wait for another request.
+	// Set a negative value to disable. Default to 30 seconds.
+	LocalServerRequestTimeout time.Duration
+	// If true, respond a bare 200 to the authorization response without the success HTML,
+	// and close the local server immediately without waiting for the response to be sent.
+	// This is useful for automated testing. Default to false.
+	LocalServerEarlyClose bool
+	// Middleware only for the authorization response, i.e. the redirect from the provider.
+	// It can reject a request before the code is processed, e.g. rate limiting.
+	// This is applied inside LocalServerMiddleware. Default to none.
+	LocalServerRedirectMiddleware func(h http.Handler) http.Handler
 	// A channel to send its URL when the local server is ready. Default to none.
 	LocalServerReadyChan chan<- string
+	// A function called with the bound address when the local server is started,
+	// e.g. to register the port to the provider.
+	// It is called synchronously before the browser is opened.
+	// It can be used with LocalServerReadyChan.
+	// If LocalServerFIFOPath is set, it is called with the path of the named pipe. Default to none.
+	LocalServerOnStarted func(addr net.Addr)
+	// A function to modify OAuth2Config after the redirect URL is determined
+	// and before the authorization URL is built.
+	// This is an escape hatch for a case which the other fields do not cover. Default to none.
+	OAuth2ConfigMutator func(cfg *oauth2.Config)
+	// If set, open the URL of the local server by it when the local server is ready.
+	// It is called in a goroutine, and an error is logged to Logger.
+	// For example, set BrowserOpenerFunc(browser.OpenURL) of github.com/pkg/browser.
+	// Default to none.
+	BrowserOpener BrowserOpener
+	// If true, copy the authorization URL to the clipboard when the local server is ready,
+	// and show a message to stderr so that the user can paste it in the browser.
+	// This is useful if the browser cannot be opened.
+	// If the clipboard is not available, the URL is shown instead. Default to false.
+	CopyAuthURLToClipboard bool
 
 	// DEPRECATED: this will be removed in the future release.
 	// Use LocalServerBindAddress instead.
@@ -69,6 +365,20 @@ type Config struct {
 	// If nil or an empty slice is given, LocalServerAddress is ignored and allocate a free port.
 	// If multiple ports are given, they are appended to LocalServerBindAddress.
 	LocalServerPort []int
+
+	// A function to compute additional options from the authorization parameters.
+	// This is set by GetTokenWithSignedOptions.
+	authCodeOptionsSigner func(authParams url.Values) ([]oauth2.AuthCodeOption, error)
+	// Deadline of the authorization. This is set if AuthorizationTimeout is set.
+	authorizationDeadline time.Time
+	// Path of the redirect URL. This is set if LocalServerRandomizePath is true.
+	redirectPath string
+	// Recorder of GetTokenStats. This is set if StatsChan is set.
+	stats *statsRecorder
+
+	// Options for testing, such as LocalServerResponseDelayForTesting.
+	// They are available only in the build with the oauth2cli_testing tag.
+	testingConfig
 }
 
 func (c *Config) validateAndSetDefaults() error {
@@ -76,35 +386,217 @@ func (c *Config) validateAndSetDefaults() error {
 		(c.LocalServerCertFile == "" && c.LocalServerKeyFile != "") {
 		return fmt.Errorf("both LocalServerCertFile and LocalServerKeyFile must be set")
 	}
+	if c.LocalServerClientCertValidator != nil && c.LocalServerCertFile == "" {
+		return fmt.Errorf("LocalServerClientCertValidator requires LocalServerCertFile and LocalServerKeyFile")
+	}
+	if c.LocalServerScheme != "" && c.LocalServerScheme != "http" && c.LocalServerScheme != "https" {
+		return fmt.Errorf("LocalServerScheme must be http or https but was %s", c.LocalServerScheme)
+	}
+	if err := c.validateCORS(); err != nil {
+		return err
+	}
 	if c.RedirectURLHostname == "" {
 		c.RedirectURLHostname = "localhost"
 	}
+	if c.LocalServerFIFOPath != "" && c.OAuth2Config.RedirectURL == "" {
+		return fmt.Errorf("OAuth2Config.RedirectURL is required if LocalServerFIFOPath is set")
+	}
+	if c.StateLength < 0 {
+		return fmt.Errorf("StateLength must not be negative")
+	}
+	if c.StateLength == 0 {
+		c.StateLength = 32
+	}
 	if c.State == "" {
-		s, err := oauth2params.NewState()
+		s, err := oauth2params.NewStateWithLength(c.StateLength)
 		if err != nil {
 			return fmt.Errorf("could not generate a state parameter: %w", err)
 		}
 		c.State = s
 	}
+	if c.LocalServerRandomizePath && c.redirectPath == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return fmt.Errorf("could not generate a redirect path: %w", err)
+		}
+		c.redirectPath = "/" + hex.EncodeToString(b)
+	}
+	if c.StateMaxLength > 0 && len(c.State) > c.StateMaxLength {
+		return fmt.Errorf("state has %d characters which exceeds StateMaxLength %d", len(c.State), c.StateMaxLength)
+	}
+	if err := c.validatePKCERegistration(); err != nil {
+		return err
+	}
+	if c.StatsInterval == 0 {
+		c.StatsInterval = defaultStatsInterval
+	}
+	if c.InterruptionRecoveryTTL == 0 {
+		c.InterruptionRecoveryTTL = defaultInterruptionRecoveryTTL
+	}
+	if c.TokenEndpointTimeout == 0 {
+		c.TokenEndpointTimeout = defaultTokenEndpointTimeout
+	}
+	if c.TokenResponseJWT && (c.TokenResponseJWKSURL == "" || c.TokenResponseJWTIssuer == "") {
+		return fmt.Errorf("TokenResponseJWKSURL and TokenResponseJWTIssuer are required if TokenResponseJWT is set")
+	}
+	if c.ResponseType != "" {
+		if c.HybridResponseType != "" && c.HybridResponseType != c.ResponseType {
+			return fmt.Errorf("ResponseType and HybridResponseType must not be different")
+		}
+		if !c.SkipResponseTypeValidation && !strings.Contains(c.ResponseType, "code") {
+			return fmt.Errorf("ResponseType must contain code but was %s", c.ResponseType)
+		}
+		c.appendAuthCodeOptions(oauth2.SetAuthURLParam("response_type", c.ResponseType))
+	} else if c.HybridResponseType != "" {
+		c.appendAuthCodeOptions(oauth2.SetAuthURLParam("response_type", c.HybridResponseType))
+	}
+	if len(c.AuthorizationDetails) > 0 {
+		opt, err := authorizationDetailsOption(c.AuthorizationDetails)
+		if err != nil {
+			return err
+		}
+		c.appendAuthCodeOptions(opt)
+	}
+	if c.LocalServerResponseBodyLimit == 0 {
+		c.LocalServerResponseBodyLimit = defaultLocalServerResponseBodyLimit
+	}
+	if c.LocalServerRequestTimeout == 0 {
+		c.LocalServerRequestTimeout = defaultLocalServerRequestTimeout
+	}
 	if c.LocalServerMiddleware == nil {
 		c.LocalServerMiddleware = noopMiddleware
 	}
+	if c.LocalServerErrorHTML == "" {
+		c.LocalServerErrorHTML = DefaultLocalServerErrorHTML
+	}
+	if _, err := template.New("").Parse(c.LocalServerErrorHTML); err != nil {
+		return fmt.Errorf("invalid LocalServerErrorHTML: %w", err)
+	}
 	if c.LocalServerSuccessHTML == "" {
 		c.LocalServerSuccessHTML = DefaultLocalServerSuccessHTML
 	}
+	if c.LocalServerAlreadyUsedHTML == "" {
+		c.LocalServerAlreadyUsedHTML = DefaultLocalServerAlreadyUsedHTML
+	}
+	if c.LocalServerTimeoutHTML == "" {
+		c.LocalServerTimeoutHTML = DefaultLocalServerTimeoutHTML
+	}
+	if _, err := template.New("").Parse(c.LocalServerTimeoutHTML); err != nil {
+		return fmt.Errorf("invalid LocalServerTimeoutHTML: %w", err)
+	}
 	return nil
 }
 
-func (c *Config) populateDeprecatedFields() {
-	if len(c.LocalServerPort) > 0 {
-		address := c.LocalServerAddress
-		if address == "" {
-			address = "127.0.0.1"
+// appendAuthCodeOptions appends the options to a copy of AuthCodeOptions.
+func (c *Config) appendAuthCodeOptions(opts ...oauth2.AuthCodeOption) {
+	c.AuthCodeOptions = append(append([]oauth2.AuthCodeOption(nil), c.AuthCodeOptions...), opts...)
+}
+
+func (c *Config) tokenExpirySlack() time.Duration {
+	if c.TokenExpirySlack == 0 {
+		return defaultTokenExpirySlack
+	}
+	if c.TokenExpirySlack < 0 {
+		return 0
+	}
+	return c.TokenExpirySlack
+}
+
+// localServerPath returns the path of the local server which receives the authorization response.
+func (c *Config) localServerPath() string {
+	if c.redirectPath == "" {
+		return "/"
+	}
+	return c.redirectPath
+}
+
+func (c *Config) isLocalServerSingleUse() bool {
+	return c.LocalServerSingleUse == nil || *c.LocalServerSingleUse
+}
+
+// mergeAdditionalScopes appends AdditionalScopes to a copy of OAuth2Config.Scopes.
+// It keeps the order and removes duplicates.
+func (c *Config) mergeAdditionalScopes() {
+	if len(c.AdditionalScopes) == 0 {
+		return
+	}
+	seen := make(map[string]bool)
+	var scopes []string
+	for _, scope := range append(append([]string(nil), c.OAuth2Config.Scopes...), c.AdditionalScopes...) {
+		if seen[scope] {
+			continue
 		}
-		for _, port := range c.LocalServerPort {
-			c.LocalServerBindAddress = append(c.LocalServerBindAddress, fmt.Sprintf("%s:%d", address, port))
+		seen[scope] = true
+		scopes = append(scopes, scope)
+	}
+	c.OAuth2Config.Scopes = scopes
+}
+
+// EffectiveScopes returns the scopes to request,
+// i.e. OAuth2Config.Scopes and AdditionalScopes sorted without duplicates.
+func (c Config) EffectiveScopes() []string {
+	seen := make(map[string]bool)
+	var scopes []string
+	for _, s := range [][]string{c.OAuth2Config.Scopes, c.AdditionalScopes} {
+		for _, scope := range s {
+			if !seen[scope] {
+				seen[scope] = true
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// HasScope returns true if the scope is in EffectiveScopes.
+func (c Config) HasScope(scope string) bool {
+	for _, s := range c.EffectiveScopes() {
+		if s == scope {
+			return true
 		}
 	}
+	return false
+}
+
+func (c *Config) populateDeprecatedFields() {
+	c.LocalServerBindAddress = append(c.LocalServerBindAddress, c.deprecatedBindAddresses()...)
+}
+
+func (c *Config) deprecatedBindAddresses() []string {
+	if len(c.LocalServerPort) == 0 {
+		return nil
+	}
+	address := c.LocalServerAddress
+	if address == "" {
+		address = "127.0.0.1"
+	}
+	var addresses []string
+	for _, port := range c.LocalServerPort {
+		addresses = append(addresses, fmt.Sprintf("%s:%d", address, port))
+	}
+	return addresses
+}
+
+// EffectiveBindAddresses returns the addresses which the local server tries to bind to, in order.
+// This includes the addresses of the deprecated LocalServerAddress and LocalServerPort,
+// and removes duplicates. If no address is given, it returns "127.0.0.1:0".
+// This does not change the config.
+func (c *Config) EffectiveBindAddresses() []string {
+	candidates := append(append([]string(nil), c.LocalServerBindAddress...), c.deprecatedBindAddresses()...)
+	seen := make(map[string]bool)
+	var addresses []string
+	for _, address := range candidates {
+		if seen[address] {
+			continue
+		}
+		seen[address] = true
+		addresses = append(addresses, address)
+	}
+	if len(addresses) == 0 {
+		return []string{"127.0.0.1:0"}
+	}
+	return addresses
 }
 
 // GetToken performs the Authorization Code Grant Flow and returns a token received from the provider.
@@ -120,17 +612,129 @@ func (c *Config) populateDeprecatedFields() {
 // 	6. Return the code.
 //
 func GetToken(ctx context.Context, config Config) (*oauth2.Token, error) {
+	result, err := GetTokenWithResult(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return result.Token, nil
+}
+
+// GetTokenResult represents a result of GetTokenWithResult.
+type GetTokenResult struct {
+	Token *oauth2.Token
+	// ID token in the authorization response, i.e. the hybrid flow.
+	// Note that it is not verified.
+	AuthorizationResponseIDToken string
+	// Network events during the token exchange.
+	// This is set only if Config.EnableNetTrace is true.
+	NetTrace *NetTrace
+}
+
+// GetTokenWithResult performs the same flow as GetToken.
+// It returns the token and diagnostic information of the flow.
+func GetTokenWithResult(ctx context.Context, config Config) (*GetTokenResult, error) {
 	if err := config.validateAndSetDefaults(); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 	config.populateDeprecatedFields()
-	code, err := receiveCodeViaLocalServer(ctx, &config)
+	config.mergeAdditionalScopes()
+	getTokenFunc := getToken
+	if config.HandleOSSignals {
+		getTokenFunc = withOSSignals(getToken)
+	}
+	if config.SessionDeduplicator != nil {
+		return deduplicateSession(ctx, &config, getTokenFunc)
+	}
+	return getTokenFunc(ctx, &config)
+}
+
+// GetTokenWithSignedOptions performs the same flow as GetToken,
+// with the authorization request options computed by an external signer such as an HSM.
+//
+// The signer is called once the local server is started, i.e. after the redirect URL is determined.
+// It receives the parameters of the authorization request and returns the options to add to them,
+// such as a PKCE challenge or a signed request object.
+func GetTokenWithSignedOptions(ctx context.Context, config Config, signer func(authParams url.Values) ([]oauth2.AuthCodeOption, error)) (*oauth2.Token, error) {
+	if signer == nil {
+		return nil, fmt.Errorf("invalid config: signer is nil")
+	}
+	config.authCodeOptionsSigner = signer
+	return GetToken(ctx, config)
+}
+
+func getToken(ctx context.Context, config *Config) (*GetTokenResult, error) {
+	if config.AuthorizationTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.AuthorizationTimeout)
+		defer cancel()
+		config.authorizationDeadline = time.Now().Add(config.AuthorizationTimeout)
+	}
+	stopStats := startStats(ctx, config)
+	defer stopStats()
+	if config.InterruptionRecoveryCache != nil {
+		if token := recoverInterruptedCode(ctx, config); token != nil {
+			return &GetTokenResult{Token: token}, nil
+		}
+	}
+	if config.PreflightOIDCCheck != nil {
+		if err := config.PreflightOIDCCheck.Check(ctx, *config); err != nil {
+			return nil, err
+		}
+	}
+	var resp *authorizationResponse
+	var err error
+	if config.LocalServerFIFOPath != "" {
+		resp, err = receiveCodeViaFIFO(ctx, config)
+	} else {
+		resp, err = receiveCodeViaLocalServer(ctx, config)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("authorization error: %w", err)
 	}
-	token, err := config.OAuth2Config.Exchange(ctx, code, config.TokenRequestOptions...)
+	if config.ValidateCHash && resp.idToken != "" {
+		if err := validateCHash(resp.idToken, resp.code); err != nil {
+			return nil, fmt.Errorf("invalid authorization response: %w", err)
+		}
+	}
+	exchangeCtx := ctx
+	var netTrace *netTraceRecorder
+	if config.EnableNetTrace {
+		netTrace = newNetTraceRecorder()
+		exchangeCtx = netTrace.withClientTrace(ctx)
+	}
+	if config.ExchangeCodeHook != nil {
+		if err := config.ExchangeCodeHook(ctx, resp.code); err != nil {
+			return nil, fmt.Errorf("exchange is aborted by the hook: %w", err)
+		}
+	}
+	config.stats.exchangeStarted()
+	token, err := exchangeCode(exchangeCtx, config, &config.OAuth2Config, resp.code)
 	if err != nil {
+		if config.InterruptionRecoveryCache != nil {
+			saveInterruptedCode(ctx, config, resp.code)
+		}
 		return nil, fmt.Errorf("could not exchange the code and token: %w", err)
 	}
-	return token, nil
+	result := GetTokenResult{Token: token, AuthorizationResponseIDToken: resp.idToken}
+	if netTrace != nil {
+		result.NetTrace = netTrace.netTrace()
+	}
+	return &result, nil
+}
+
+// exchangeCode exchanges the code within Config.TokenEndpointTimeout.
+func exchangeCode(ctx context.Context, c *Config, oauth2Config *oauth2.Config, code string) (*oauth2.Token, error) {
+	ctx, closeIdleConnections := withTokenEndpointKeepAlive(ctx, c)
+	defer closeIdleConnections()
+	ctx = withTokenResponseJWT(ctx, c, oauth2Config.ClientID)
+	if c.TokenEndpointTimeout <= 0 {
+		return oauth2Config.Exchange(ctx, code, c.TokenRequestOptions...)
+	}
+	exchangeCtx, cancel := context.WithTimeout(ctx, c.TokenEndpointTimeout)
+	defer cancel()
+	token, err := oauth2Config.Exchange(exchangeCtx, code, c.TokenRequestOptions...)
+	if err != nil && ctx.Err() == nil && errors.Is(exchangeCtx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("token request timed out after %s: %w", c.TokenEndpointTimeout, err)
+	}
+	return token, err
 }
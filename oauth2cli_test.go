@@ -1,9 +1,17 @@
 package oauth2cli
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"golang.org/x/oauth2"
 )
 
 func TestConfig_populateDeprecatedFields(t *testing.T) {
@@ -98,3 +106,165 @@ func TestConfig_populateDeprecatedFields(t *testing.T) {
 		})
 	})
 }
+
+func TestConfig_validateAndSetDefaults(t *testing.T) {
+	t.Run("StateLength", func(t *testing.T) {
+		cfg := Config{StateLength: 16}
+		if err := cfg.validateAndSetDefaults(); err != nil {
+			t.Fatalf("validateAndSetDefaults error: %s", err)
+		}
+		if len(cfg.State) != 22 {
+			t.Errorf("length of State wants 22 but was %d", len(cfg.State))
+		}
+	})
+
+	t.Run("StateMaxLength", func(t *testing.T) {
+		cfg := Config{StateMaxLength: 32}
+		if err := cfg.validateAndSetDefaults(); err == nil {
+			t.Errorf("validateAndSetDefaults wants error but was nil")
+		}
+		cfg = Config{StateLength: 16, StateMaxLength: 32}
+		if err := cfg.validateAndSetDefaults(); err != nil {
+			t.Errorf("validateAndSetDefaults error: %s", err)
+		}
+	})
+}
+
+func TestConfig_mergeAdditionalScopes(t *testing.T) {
+	t.Run("NoAdditionalScopes", func(t *testing.T) {
+		cfg := Config{OAuth2Config: oauth2.Config{Scopes: []string{"openid", "email"}}}
+		cfg.mergeAdditionalScopes()
+		want := []string{"openid", "email"}
+		if diff := cmp.Diff(want, cfg.OAuth2Config.Scopes); diff != "" {
+			t.Errorf("Scopes mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("Merge", func(t *testing.T) {
+		baseScopes := []string{"openid", "email"}
+		cfg := Config{
+			OAuth2Config:     oauth2.Config{Scopes: baseScopes},
+			AdditionalScopes: []string{"profile", "email", "offline_access", "profile"},
+		}
+		cfg.mergeAdditionalScopes()
+		want := []string{"openid", "email", "profile", "offline_access"}
+		if diff := cmp.Diff(want, cfg.OAuth2Config.Scopes); diff != "" {
+			t.Errorf("Scopes mismatch (-want +got):\n%s", diff)
+		}
+		if diff := cmp.Diff([]string{"openid", "email"}, baseScopes); diff != "" {
+			t.Errorf("base scopes must not be changed (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestConfig_EffectiveBindAddresses(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		cfg  Config
+		want []string
+	}{
+		{"Default", Config{}, []string{"127.0.0.1:0"}},
+		{"BindAddress", Config{LocalServerBindAddress: []string{"127.0.0.1:8000", "127.0.0.1:18000"}}, []string{"127.0.0.1:8000", "127.0.0.1:18000"}},
+		{"DeprecatedPort", Config{LocalServerAddress: "0.0.0.0", LocalServerPort: []int{8000}}, []string{"0.0.0.0:8000"}},
+		{"Deduplicate", Config{
+			LocalServerBindAddress: []string{"127.0.0.1:8000", "127.0.0.1:8000"},
+			LocalServerPort:        []int{8000, 18000},
+		}, []string{"127.0.0.1:8000", "127.0.0.1:18000"}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			bindAddress := append([]string(nil), c.cfg.LocalServerBindAddress...)
+			got := c.cfg.EffectiveBindAddresses()
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("EffectiveBindAddresses mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(bindAddress, c.cfg.LocalServerBindAddress); diff != "" {
+				t.Errorf("LocalServerBindAddress must not be changed (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_exchangeCode(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(200 * time.Millisecond):
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"access_token":"ACCESS_TOKEN","token_type":"Bearer"}`)
+	}))
+	defer s.Close()
+	oauth2Config := oauth2.Config{ClientID: "YOUR_CLIENT_ID", Endpoint: oauth2.Endpoint{TokenURL: s.URL}}
+
+	t.Run("Timeout", func(t *testing.T) {
+		cfg := Config{TokenEndpointTimeout: 10 * time.Millisecond}
+		_, err := exchangeCode(context.TODO(), &cfg, &oauth2Config, "AUTH_CODE")
+		if err == nil || !strings.Contains(err.Error(), "timed out") {
+			t.Errorf("exchangeCode wants a timeout error but was %v", err)
+		}
+	})
+	t.Run("Disabled", func(t *testing.T) {
+		cfg := Config{TokenEndpointTimeout: -1}
+		token, err := exchangeCode(context.TODO(), &cfg, &oauth2Config, "AUTH_CODE")
+		if err != nil {
+			t.Fatalf("exchangeCode error: %s", err)
+		}
+		if w := "ACCESS_TOKEN"; token.AccessToken != w {
+			t.Errorf("AccessToken wants %s but was %s", w, token.AccessToken)
+		}
+	})
+}
+
+func TestConfig_EffectiveScopes(t *testing.T) {
+	cfg := Config{
+		OAuth2Config:     oauth2.Config{Scopes: []string{"openid", "email"}},
+		AdditionalScopes: []string{"profile", "email", "offline_access"},
+	}
+	want := []string{"email", "offline_access", "openid", "profile"}
+	if diff := cmp.Diff(want, cfg.EffectiveScopes()); diff != "" {
+		t.Errorf("EffectiveScopes mismatch (-want +got):\n%s", diff)
+	}
+	if !cfg.HasScope("offline_access") {
+		t.Errorf("HasScope(offline_access) wants true but was false")
+	}
+	if cfg.HasScope("admin") {
+		t.Errorf("HasScope(admin) wants false but was true")
+	}
+}
+
+func TestConfig_ResponseType(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		cfg     Config
+		want    string
+		wantErr bool
+	}{
+		{"Default", Config{}, "code", false},
+		{"Custom", Config{ResponseType: "code token"}, "code token", false},
+		{"WithoutCode", Config{ResponseType: "token"}, "", true},
+		{"SkipValidation", Config{ResponseType: "token", SkipResponseTypeValidation: true}, "token", false},
+		{"Hybrid", Config{HybridResponseType: "code id_token"}, "code id_token", false},
+		{"ConflictWithHybrid", Config{ResponseType: "code token", HybridResponseType: "code id_token"}, "", true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := c.cfg
+			err := cfg.validateAndSetDefaults()
+			if c.wantErr {
+				if err == nil {
+					t.Errorf("validateAndSetDefaults wants error but was nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateAndSetDefaults error: %s", err)
+			}
+			u, err := url.Parse(cfg.OAuth2Config.AuthCodeURL(cfg.State, cfg.AuthCodeOptions...))
+			if err != nil {
+				t.Fatalf("invalid authorization URL: %s", err)
+			}
+			if got := u.Query().Get("response_type"); got != c.want {
+				t.Errorf("response_type wants %s but was %s", c.want, got)
+			}
+		})
+	}
+}
@@ -14,7 +14,16 @@ import (
 // NewState returns a state parameter.
 // This generates 256 bits of random bytes.
 func NewState() (string, error) {
-	b, err := random(32)
+	return NewStateWithLength(32)
+}
+
+// NewStateWithLength returns a state parameter of the given number of random bytes.
+// It is encoded in base64url without padding, i.e. it contains only [A-Za-z0-9_-].
+func NewStateWithLength(n int) (string, error) {
+	if n <= 0 {
+		return "", fmt.Errorf("length must be positive but was %d", n)
+	}
+	b, err := random(n)
 	if err != nil {
 		return "", fmt.Errorf("could not generate a random: %w", err)
 	}
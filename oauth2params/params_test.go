@@ -1,6 +1,7 @@
 package oauth2params
 
 import (
+	"regexp"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -24,3 +25,19 @@ func Test_computeS256(t *testing.T) {
 		t.Errorf("mismatch (-want +got):\n%s", diff)
 	}
 }
+
+func TestNewStateWithLength(t *testing.T) {
+	s, err := NewStateWithLength(32)
+	if err != nil {
+		t.Fatalf("NewStateWithLength error: %s", err)
+	}
+	if len(s) != 43 {
+		t.Errorf("length wants 43 but was %d", len(s))
+	}
+	if !regexp.MustCompile(`^[A-Za-z0-9_-]+$`).MatchString(s) {
+		t.Errorf("state wants base64url without padding but was %s", s)
+	}
+	if _, err := NewStateWithLength(0); err == nil {
+		t.Errorf("NewStateWithLength wants error but was nil")
+	}
+}
@@ -0,0 +1,184 @@
+// Package oidcflow provides OpenID Connect authentication in a single call.
+// It bundles the discovery, PKCE, nonce and ID token verification on top of oauth2cli.
+//
+// This is the recommended entry point if you need only an ID token of the user.
+package oidcflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/int128/oauth2cli"
+	"github.com/int128/oauth2cli/internal/jose"
+	"github.com/int128/oauth2cli/oauth2params"
+	"golang.org/x/oauth2"
+)
+
+// Config represents a config for GetIDToken.
+type Config struct {
+	// Issuer URL of the provider, e.g. https://accounts.google.com.
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// Scopes in addition to openid, e.g. email.
+	Scopes []string
+	// Config of oauth2cli.GetToken, such as LocalServerReadyChan.
+	// OAuth2Config is overwritten by the above fields and the discovery document.
+	GetTokenConfig oauth2cli.Config
+}
+
+// IDTokenResult represents a result of GetIDToken.
+type IDTokenResult struct {
+	// Raw ID token.
+	IDToken string
+	// Token response including the access token and refresh token.
+	Token *oauth2.Token
+	// Claims of the verified ID token.
+	Claims  map[string]interface{}
+	Subject string
+	Expiry  time.Time
+}
+
+// providerMetadata represents a subset of the discovery document.
+// See https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// GetIDToken performs the OpenID Connect authentication and returns the verified ID token.
+//
+// This performs the following steps:
+//
+//  1. Fetch the discovery document of the issuer.
+//  2. Get a token by oauth2cli.GetToken with PKCE (S256) and a nonce.
+//  3. Verify the signature of the ID token by the JWKS of the issuer.
+//  4. Verify the iss, aud, exp and nonce claims.
+//
+// The HTTP client in the context is used as well as golang.org/x/oauth2.
+func GetIDToken(ctx context.Context, cfg Config) (*IDTokenResult, error) {
+	if cfg.Issuer == "" || cfg.ClientID == "" {
+		return nil, errors.New("invalid config: Issuer and ClientID must be set")
+	}
+	client := contextClient(ctx)
+	metadata, err := discover(ctx, client, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("discovery error: %w", err)
+	}
+	pkce, err := oauth2params.NewPKCE()
+	if err != nil {
+		return nil, fmt.Errorf("could not generate PKCE parameters: %w", err)
+	}
+	nonce, err := oauth2params.NewState()
+	if err != nil {
+		return nil, fmt.Errorf("could not generate a nonce: %w", err)
+	}
+
+	getTokenConfig := cfg.GetTokenConfig
+	getTokenConfig.OAuth2Config = oauth2.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  metadata.AuthorizationEndpoint,
+			TokenURL: metadata.TokenEndpoint,
+		},
+		Scopes: []string{"openid"},
+	}
+	getTokenConfig.AdditionalScopes = append(append([]string(nil), getTokenConfig.AdditionalScopes...), cfg.Scopes...)
+	getTokenConfig.AuthCodeOptions = append(append(append([]oauth2.AuthCodeOption(nil), getTokenConfig.AuthCodeOptions...),
+		pkce.AuthCodeOptions()...), oauth2.SetAuthURLParam("nonce", nonce))
+	getTokenConfig.TokenRequestOptions = append(append([]oauth2.AuthCodeOption(nil), getTokenConfig.TokenRequestOptions...),
+		pkce.TokenRequestOptions()...)
+	token, err := oauth2cli.GetToken(ctx, getTokenConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, ok := token.Extra("id_token").(string)
+	if !ok || idToken == "" {
+		return nil, errors.New("id_token is missing in the token response")
+	}
+	jwks, err := jose.FetchJWKS(ctx, client, metadata.JWKSURI)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch the JWKS: %w", err)
+	}
+	claims, err := jose.Verify(idToken, jwks)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+	result, err := validateClaims(claims, metadata.Issuer, cfg.ClientID, nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+	result.IDToken = idToken
+	result.Token = token
+	return result, nil
+}
+
+func discover(ctx context.Context, client *http.Client, issuer string) (*providerMetadata, error) {
+	b, err := get(ctx, client, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration")
+	if err != nil {
+		return nil, err
+	}
+	var m providerMetadata
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("invalid discovery document: %w", err)
+	}
+	if strings.TrimSuffix(m.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return nil, fmt.Errorf("issuer wants %s but was %s", issuer, m.Issuer)
+	}
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+		return nil, errors.New("discovery document has no authorization_endpoint, token_endpoint or jwks_uri")
+	}
+	return &m, nil
+}
+
+func get(ctx context.Context, client *http.Client, u string) ([]byte, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create a request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("could not send a request: %w", err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the response body: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("%s returned status %d", u, resp.StatusCode)
+	}
+	return b, nil
+}
+
+// validateClaims verifies the claims of the ID token.
+// See https://openid.net/specs/openid-connect-core-1_0.html#IDTokenValidation
+func validateClaims(claims map[string]interface{}, issuer, clientID, nonce string) (*IDTokenResult, error) {
+	expiry, err := jose.ValidateClaims(claims, issuer, clientID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return nil, errors.New("nonce does not match")
+	}
+	sub, _ := claims["sub"].(string)
+	return &IDTokenResult{Claims: claims, Subject: sub, Expiry: expiry}, nil
+}
+
+func contextClient(ctx context.Context) *http.Client {
+	if c, ok := ctx.Value(oauth2.HTTPClient).(*http.Client); ok && c != nil {
+		return c
+	}
+	return http.DefaultClient
+}
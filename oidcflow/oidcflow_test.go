@@ -0,0 +1,159 @@
+package oidcflow
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/int128/oauth2cli"
+)
+
+func TestGetIDToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("could not generate a key: %s", err)
+	}
+	var mu sync.Mutex
+	var nonce string
+	mux := http.NewServeMux()
+	s := httptest.NewServer(mux)
+	defer s.Close()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 s.URL,
+			"authorization_endpoint": s.URL + "/authorize",
+			"token_endpoint":         s.URL + "/token",
+			"jwks_uri":               s.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "KEY_ID",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if w := "S256"; q.Get("code_challenge_method") != w {
+			t.Errorf("code_challenge_method wants %s but was %s", w, q.Get("code_challenge_method"))
+		}
+		if w := "openid email"; q.Get("scope") != w {
+			t.Errorf("scope wants %s but was %s", w, q.Get("scope"))
+		}
+		mu.Lock()
+		nonce = q.Get("nonce")
+		mu.Unlock()
+		http.Redirect(w, r, q.Get("redirect_uri")+"?"+url.Values{"code": {"AUTH_CODE"}, "state": {q.Get("state")}}.Encode(), 302)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code_verifier") == "" {
+			t.Errorf("code_verifier is missing")
+		}
+		mu.Lock()
+		idToken := signRS256(t, key, map[string]interface{}{
+			"iss":   s.URL,
+			"sub":   "SUBJECT",
+			"aud":   "YOUR_CLIENT_ID",
+			"nonce": nonce,
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		})
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "ACCESS_TOKEN",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	})
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+	defer cancel()
+	readyCh := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(<-readyCh)
+		if err != nil {
+			t.Errorf("could not open browser request: %s", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+	result, err := GetIDToken(ctx, Config{
+		Issuer:         s.URL,
+		ClientID:       "YOUR_CLIENT_ID",
+		Scopes:         []string{"email"},
+		GetTokenConfig: oauth2cli.Config{LocalServerReadyChan: readyCh},
+	})
+	if err != nil {
+		t.Fatalf("GetIDToken error: %s", err)
+	}
+	if w := "SUBJECT"; result.Subject != w {
+		t.Errorf("Subject wants %s but was %s", w, result.Subject)
+	}
+	if w := "ACCESS_TOKEN"; result.Token.AccessToken != w {
+		t.Errorf("AccessToken wants %s but was %s", w, result.Token.AccessToken)
+	}
+}
+
+func TestValidateClaims(t *testing.T) {
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":   "https://issuer.example.com",
+			"sub":   "SUBJECT",
+			"aud":   []interface{}{"YOUR_CLIENT_ID", "ANOTHER"},
+			"nonce": "NONCE",
+			"exp":   float64(time.Now().Add(time.Hour).Unix()),
+		}
+	}
+	if _, err := validateClaims(valid(), "https://issuer.example.com", "YOUR_CLIENT_ID", "NONCE"); err != nil {
+		t.Errorf("validateClaims error: %s", err)
+	}
+	for name, mutate := range map[string]func(claims map[string]interface{}){
+		"Issuer":   func(claims map[string]interface{}) { claims["iss"] = "https://evil.example.com" },
+		"Audience": func(claims map[string]interface{}) { claims["aud"] = "ANOTHER" },
+		"Expired":  func(claims map[string]interface{}) { claims["exp"] = float64(time.Now().Add(-time.Hour).Unix()) },
+		"Nonce":    func(claims map[string]interface{}) { claims["nonce"] = "REPLAYED" },
+	} {
+		t.Run(name, func(t *testing.T) {
+			claims := valid()
+			mutate(claims)
+			if _, err := validateClaims(claims, "https://issuer.example.com", "YOUR_CLIENT_ID", "NONCE"); err == nil {
+				t.Errorf("validateClaims wants error but was nil")
+			}
+		})
+	}
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, claims map[string]interface{}) string {
+	signingInput := encodeJSONPart(t, map[string]string{"alg": "RS256", "kid": "KEY_ID", "typ": "JWT"}) + "." + encodeJSONPart(t, claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("could not sign: %s", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func encodeJSONPart(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("could not encode json: %s", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,28 @@
+package oauth2cli
+
+import (
+	"log/slog"
+	"os"
+)
+
+// Option is a function to modify a Config.
+type Option func(c *Config)
+
+// ApplyOption returns a copy of the config with the options applied.
+func ApplyOption(c Config, opts ...Option) Config {
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// WithDebugMode returns an Option which enables the diagnostics for investigation.
+// It sets Logger to write to stderr at the debug level,
+// and enables EnableNetTrace and LocalServerVerboseHeaders.
+func WithDebugMode() Option {
+	return func(c *Config) {
+		c.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		c.EnableNetTrace = true
+		c.LocalServerVerboseHeaders = true
+	}
+}
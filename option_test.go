@@ -0,0 +1,27 @@
+package oauth2cli
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestApplyOption(t *testing.T) {
+	cfg := Config{State: "STATE"}
+	got := ApplyOption(cfg, WithDebugMode())
+	if cfg.Logger != nil || cfg.EnableNetTrace {
+		t.Errorf("ApplyOption wants the original config unchanged but was %+v", cfg)
+	}
+	if got.Logger == nil || !got.Logger.Enabled(context.TODO(), slog.LevelDebug) {
+		t.Errorf("Logger wants the debug level")
+	}
+	if !got.EnableNetTrace {
+		t.Errorf("EnableNetTrace wants true")
+	}
+	if !got.LocalServerVerboseHeaders {
+		t.Errorf("LocalServerVerboseHeaders wants true")
+	}
+	if got.State != "STATE" {
+		t.Errorf("State wants STATE but was %s", got.State)
+	}
+}
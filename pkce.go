@@ -0,0 +1,47 @@
+package oauth2cli
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// ConfigurationError represents an error of the config which is detected before the flow.
+type ConfigurationError struct {
+	// Name of the field, e.g. PKCERegisteredMethods.
+	Field   string
+	Message string
+}
+
+func (e *ConfigurationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// validatePKCERegistration verifies that the PKCE method in AuthCodeOptions is registered.
+func (c *Config) validatePKCERegistration() error {
+	if !c.PKCERegistrationEnabled {
+		return nil
+	}
+	u, err := url.Parse(c.OAuth2Config.AuthCodeURL(c.State, c.AuthCodeOptions...))
+	if err != nil {
+		return fmt.Errorf("invalid authorization URL: %w", err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge") == "" {
+		return &ConfigurationError{Field: "AuthCodeOptions", Message: "PKCE is required by PKCERegistrationEnabled but code_challenge is not set"}
+	}
+	// the default method is plain
+	// https://tools.ietf.org/html/rfc7636#section-4.3
+	method := q.Get("code_challenge_method")
+	if method == "" {
+		method = "plain"
+	}
+	for _, registered := range c.PKCERegisteredMethods {
+		if registered == method {
+			return nil
+		}
+	}
+	return &ConfigurationError{
+		Field:   "PKCERegisteredMethods",
+		Message: fmt.Sprintf("code_challenge_method %s is not registered (registered: %v)", method, c.PKCERegisteredMethods),
+	}
+}
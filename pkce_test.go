@@ -0,0 +1,53 @@
+package oauth2cli
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/int128/oauth2cli/oauth2params"
+	"golang.org/x/oauth2"
+)
+
+func TestConfig_validatePKCERegistration(t *testing.T) {
+	pkce, err := oauth2params.NewPKCE()
+	if err != nil {
+		t.Fatalf("NewPKCE error: %s", err)
+	}
+	for _, c := range []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"Disabled", Config{}, false},
+		{"Registered", Config{
+			PKCERegistrationEnabled: true,
+			PKCERegisteredMethods:   []string{"S256"},
+			AuthCodeOptions:         pkce.AuthCodeOptions(),
+		}, false},
+		{"NotRegistered", Config{
+			PKCERegistrationEnabled: true,
+			PKCERegisteredMethods:   []string{"plain"},
+			AuthCodeOptions:         pkce.AuthCodeOptions(),
+		}, true},
+		{"DefaultPlain", Config{
+			PKCERegistrationEnabled: true,
+			PKCERegisteredMethods:   []string{"plain"},
+			AuthCodeOptions:         []oauth2.AuthCodeOption{oauth2.SetAuthURLParam("code_challenge", "VERIFIER")},
+		}, false},
+		{"NoPKCE", Config{
+			PKCERegistrationEnabled: true,
+			PKCERegisteredMethods:   []string{"S256"},
+		}, true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.cfg.validatePKCERegistration()
+			if (err != nil) != c.wantErr {
+				t.Fatalf("validatePKCERegistration wants error=%v but was %v", c.wantErr, err)
+			}
+			var configErr *ConfigurationError
+			if err != nil && !errors.As(err, &configErr) {
+				t.Errorf("error wants ConfigurationError but was %T", err)
+			}
+		})
+	}
+}
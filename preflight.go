@@ -0,0 +1,94 @@
+package oauth2cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PreflightOIDCCheck verifies the endpoints of the provider before the flow.
+type PreflightOIDCCheck interface {
+	// Check returns an error if an endpoint of the config is not available.
+	Check(ctx context.Context, cfg Config) error
+}
+
+// PreflightError represents an error of the endpoint on the preflight check.
+type PreflightError struct {
+	// Name of the endpoint, i.e. authorization or token.
+	Endpoint string
+	URL      string
+	Err      error
+}
+
+func (e *PreflightError) Error() string {
+	return fmt.Sprintf("preflight check of the %s endpoint (%s): %s", e.Endpoint, e.URL, e.Err)
+}
+
+func (e *PreflightError) Unwrap() error { return e.Err }
+
+// NewStandardPreflightCheck returns a PreflightOIDCCheck which
+// sends a GET request to the authorization endpoint and expects 200 or 302,
+// and then sends a POST request with a dummy code to the token endpoint and expects an error response,
+// i.e. 400 or 401 of RFC 6749 section 5.2.
+// Each request is limited by the timeout. If the timeout is zero or negative, it is not limited.
+//
+// The HTTP client in the context is used as well as golang.org/x/oauth2.
+func NewStandardPreflightCheck(timeout time.Duration) PreflightOIDCCheck {
+	return &standardPreflightCheck{timeout: timeout}
+}
+
+type standardPreflightCheck struct {
+	timeout time.Duration
+}
+
+func (p *standardPreflightCheck) Check(ctx context.Context, cfg Config) error {
+	client := *contextClient(ctx)
+	// a redirect of the authorization endpoint is expected
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+	authURL := cfg.OAuth2Config.AuthCodeURL("preflight")
+	if err := p.do(ctx, &client, "GET", authURL, nil, 200, 302); err != nil {
+		return &PreflightError{Endpoint: "authorization", URL: cfg.OAuth2Config.Endpoint.AuthURL, Err: err}
+	}
+	form := url.Values{
+		"grant_type": {"authorization_code"},
+		"code":       {"preflight"},
+		"client_id":  {cfg.OAuth2Config.ClientID},
+	}
+	tokenURL := cfg.OAuth2Config.Endpoint.TokenURL
+	if err := p.do(ctx, &client, "POST", tokenURL, strings.NewReader(form.Encode()), 400, 401); err != nil {
+		return &PreflightError{Endpoint: "token", URL: tokenURL, Err: err}
+	}
+	return nil
+}
+
+func (p *standardPreflightCheck) do(ctx context.Context, client *http.Client, method, u string, body io.Reader, wantStatus ...int) error {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return fmt.Errorf("could not create a request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not send a request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	for _, status := range wantStatus {
+		if resp.StatusCode == status {
+			return nil
+		}
+	}
+	return fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
@@ -0,0 +1,62 @@
+package oauth2cli
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestStandardPreflightCheck(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/auth", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/login", 302)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.FormValue("grant_type") != "authorization_code" {
+			t.Errorf("token request wants POST of authorization_code but was %s %s", r.Method, r.FormValue("grant_type"))
+		}
+		http.Error(w, `{"error":"invalid_grant"}`, 400)
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "server error", 500)
+	})
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	for _, c := range []struct {
+		name         string
+		authURL      string
+		tokenURL     string
+		wantEndpoint string
+	}{
+		{"OK", s.URL + "/auth", s.URL + "/token", ""},
+		{"AuthorizationEndpointBroken", s.URL + "/broken", s.URL + "/token", "authorization"},
+		{"TokenEndpointBroken", s.URL + "/auth", s.URL + "/broken", "token"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := Config{OAuth2Config: oauth2.Config{
+				ClientID: "YOUR_CLIENT_ID",
+				Endpoint: oauth2.Endpoint{AuthURL: c.authURL, TokenURL: c.tokenURL},
+			}}
+			err := NewStandardPreflightCheck(time.Second).Check(context.TODO(), cfg)
+			if c.wantEndpoint == "" {
+				if err != nil {
+					t.Errorf("Check error: %s", err)
+				}
+				return
+			}
+			var preflightErr *PreflightError
+			if !errors.As(err, &preflightErr) {
+				t.Fatalf("Check wants PreflightError but was %v", err)
+			}
+			if preflightErr.Endpoint != c.wantEndpoint {
+				t.Errorf("Endpoint wants %s but was %s", c.wantEndpoint, preflightErr.Endpoint)
+			}
+		})
+	}
+}
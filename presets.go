@@ -0,0 +1,76 @@
+package oauth2cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/int128/oauth2cli/oauth2params"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/endpoints"
+)
+
+// Google returns a Config of Google.
+// The scopes default to openid, email and profile.
+// See https://developers.google.com/identity/protocols/oauth2/native-app
+func Google(clientID, clientSecret string, scopes ...string) (Config, error) {
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+	return newPresetConfig(endpoints.Google, clientID, clientSecret, scopes)
+}
+
+// GitHub returns a Config of GitHub.
+// See https://docs.github.com/en/apps/oauth-apps/building-oauth-apps/authorizing-oauth-apps
+func GitHub(clientID, clientSecret string, scopes ...string) (Config, error) {
+	return newPresetConfig(endpoints.GitHub, clientID, clientSecret, scopes)
+}
+
+// Microsoft returns a Config of the Microsoft identity platform.
+// The tenant defaults to common. The scopes default to openid, email, profile and offline_access.
+// See https://learn.microsoft.com/en-us/entra/identity-platform/v2-oauth2-auth-code-flow
+func Microsoft(tenantID, clientID, clientSecret string, scopes ...string) (Config, error) {
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile", "offline_access"}
+	}
+	return newPresetConfig(endpoints.AzureAD(tenantID), clientID, clientSecret, scopes)
+}
+
+// Okta returns a Config of the default authorization server of the Okta domain, e.g. example.okta.com.
+// The scopes default to openid, email, profile and offline_access.
+// See https://developer.okta.com/docs/guides/implement-grant-type/authcodepkce/main/
+func Okta(domain, clientID, clientSecret string, scopes ...string) (Config, error) {
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile", "offline_access"}
+	}
+	domain = strings.TrimSuffix(strings.TrimPrefix(domain, "https://"), "/")
+	issuer := "https://" + domain + "/oauth2/default"
+	endpoint := oauth2.Endpoint{
+		AuthURL:  issuer + "/v1/authorize",
+		TokenURL: issuer + "/v1/token",
+	}
+	return newPresetConfig(endpoint, clientID, clientSecret, scopes)
+}
+
+// newPresetConfig returns a Config of the endpoint.
+// If the client secret is empty, i.e. a public client, the PKCE parameters are set.
+// They are generated on each call, so call a preset for each GetToken.
+// It returns an error if the PKCE parameters could not be generated.
+func newPresetConfig(endpoint oauth2.Endpoint, clientID, clientSecret string, scopes []string) (Config, error) {
+	cfg := Config{
+		OAuth2Config: oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Endpoint:     endpoint,
+			Scopes:       scopes,
+		},
+	}
+	if clientSecret == "" {
+		pkce, err := oauth2params.NewPKCE()
+		if err != nil {
+			return Config{}, fmt.Errorf("could not generate PKCE parameters: %w", err)
+		}
+		cfg.AuthCodeOptions = pkce.AuthCodeOptions()
+		cfg.TokenRequestOptions = pkce.TokenRequestOptions()
+	}
+	return cfg, nil
+}
@@ -0,0 +1,49 @@
+package oauth2cli
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestPresets(t *testing.T) {
+	for _, c := range []struct {
+		name       string
+		preset     func() (Config, error)
+		wantAuth   string
+		wantScopes []string
+		wantPKCE   bool
+	}{
+		{"Google", func() (Config, error) { return Google("CLIENT_ID", "") }, "https://accounts.google.com/o/oauth2/auth", []string{"openid", "email", "profile"}, true},
+		{"GoogleWithScopes", func() (Config, error) { return Google("CLIENT_ID", "", "openid") }, "https://accounts.google.com/o/oauth2/auth", []string{"openid"}, true},
+		{"GitHub", func() (Config, error) { return GitHub("CLIENT_ID", "SECRET", "read:user") }, "https://github.com/login/oauth/authorize", []string{"read:user"}, false},
+		{"Microsoft", func() (Config, error) { return Microsoft("TENANT_ID", "CLIENT_ID", "") }, "https://login.microsoftonline.com/TENANT_ID/oauth2/v2.0/authorize", []string{"openid", "email", "profile", "offline_access"}, true},
+		{"Okta", func() (Config, error) { return Okta("https://example.okta.com/", "CLIENT_ID", "") }, "https://example.okta.com/oauth2/default/v1/authorize", []string{"openid", "email", "profile", "offline_access"}, true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			cfg, err := c.preset()
+			if err != nil {
+				t.Fatalf("preset error: %s", err)
+			}
+			if cfg.PKCERegistrationEnabled || cfg.PKCERegisteredMethods != nil {
+				t.Errorf("PKCE registration wants unset but was %v %v", cfg.PKCERegistrationEnabled, cfg.PKCERegisteredMethods)
+			}
+			if got := cfg.OAuth2Config.Endpoint.AuthURL; got != c.wantAuth {
+				t.Errorf("AuthURL wants %s but was %s", c.wantAuth, got)
+			}
+			if got := cfg.OAuth2Config.Scopes; !reflect.DeepEqual(got, c.wantScopes) {
+				t.Errorf("Scopes wants %v but was %v", c.wantScopes, got)
+			}
+			if err := cfg.validateAndSetDefaults(); err != nil {
+				t.Fatalf("validateAndSetDefaults error: %s", err)
+			}
+			u, err := url.Parse(cfg.OAuth2Config.AuthCodeURL("STATE", cfg.AuthCodeOptions...))
+			if err != nil {
+				t.Fatalf("invalid URL: %s", err)
+			}
+			if got := u.Query().Get("code_challenge_method") == "S256"; got != c.wantPKCE {
+				t.Errorf("PKCE wants %v but was %v", c.wantPKCE, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package oauth2cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// proxyProtoListener is a listener which reads the PROXY protocol header of each connection.
+// See https://www.haproxy.org/download/2.0/doc/proxy-protocol.txt
+type proxyProtoListener struct {
+	net.Listener
+}
+
+func (l *proxyProtoListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return newProxyProtoConn(conn), nil
+}
+
+// proxyProtoConn is a connection which has the PROXY protocol header.
+// The header is parsed on the first call of Read or RemoteAddr,
+// so that Accept does not block on a slow client.
+type proxyProtoConn struct {
+	net.Conn
+	r          *bufio.Reader
+	once       sync.Once
+	remoteAddr net.Addr
+	err        error
+}
+
+func newProxyProtoConn(conn net.Conn) *proxyProtoConn {
+	return &proxyProtoConn{Conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *proxyProtoConn) parseHeader() {
+	c.once.Do(func() {
+		addr, err := readProxyProtoHeader(c.r)
+		if err != nil {
+			c.err = fmt.Errorf("invalid PROXY protocol header: %w", err)
+			return
+		}
+		c.remoteAddr = addr
+	})
+}
+
+func (c *proxyProtoConn) Read(b []byte) (int, error) {
+	c.parseHeader()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+// RemoteAddr returns the address of the client in the header.
+// It returns the address of the peer if the header has no address, e.g. a health check of the proxy.
+func (c *proxyProtoConn) RemoteAddr() net.Addr {
+	c.parseHeader()
+	if c.remoteAddr != nil {
+		return c.remoteAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+var proxyProtoV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// readProxyProtoHeader reads the header of version 1 or 2.
+// It returns nil address if the header does not contain an address.
+func readProxyProtoHeader(r *bufio.Reader) (net.Addr, error) {
+	b, err := r.Peek(len(proxyProtoV2Signature))
+	if err != nil {
+		return nil, fmt.Errorf("could not read the header: %w", err)
+	}
+	switch {
+	case bytes.Equal(b, proxyProtoV2Signature):
+		return readProxyProtoV2Header(r)
+	case bytes.HasPrefix(b, []byte("PROXY ")):
+		return readProxyProtoV1Header(r)
+	}
+	return nil, errors.New("no PROXY protocol signature")
+}
+
+// readProxyProtoV1Header reads a header such as "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n".
+func readProxyProtoV1Header(r *bufio.Reader) (net.Addr, error) {
+	const maxLength = 107
+	var line []byte
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) >= maxLength {
+			return nil, errors.New("header is too long")
+		}
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("could not read the header: %w", err)
+		}
+		line = append(line, c)
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("invalid header %q", line)
+	}
+	ip := net.ParseIP(fields[2])
+	if ip == nil {
+		return nil, fmt.Errorf("invalid source address %s", fields[2])
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source port %s: %w", fields[4], err)
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readProxyProtoV2Header reads a header of the binary format.
+func readProxyProtoV2Header(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("could not read the header: %w", err)
+	}
+	verCmd, family := header[12], header[13]
+	if verCmd>>4 != 2 {
+		return nil, fmt.Errorf("unknown version %d", verCmd>>4)
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, fmt.Errorf("could not read the addresses: %w", err)
+	}
+	if verCmd&0xf == 0 {
+		// LOCAL command, e.g. a health check of the proxy
+		return nil, nil
+	}
+	switch family {
+	case 0x11: // TCP over IPv4
+		if len(payload) < 12 {
+			return nil, errors.New("addresses are too short")
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:10]))}, nil
+	case 0x21: // TCP over IPv6
+		if len(payload) < 36 {
+			return nil, errors.New("addresses are too short")
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:34]))}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,61 @@
+package oauth2cli
+
+import (
+	"io/ioutil"
+	"net"
+	"testing"
+)
+
+func TestProxyProtoConn(t *testing.T) {
+	v2Header := func(verCmd, family byte, addrs []byte) string {
+		b := append([]byte(nil), proxyProtoV2Signature...)
+		b = append(b, verCmd, family, byte(len(addrs)>>8), byte(len(addrs)))
+		return string(append(b, addrs...))
+	}
+	for _, c := range []struct {
+		name           string
+		header         string
+		wantRemoteAddr string
+	}{
+		{"V1TCP4", "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n", "192.168.0.1:56324"},
+		{"V1TCP6", "PROXY TCP6 2001:db8::1 2001:db8::2 56324 443\r\n", "[2001:db8::1]:56324"},
+		{"V1Unknown", "PROXY UNKNOWN\r\n", "pipe"},
+		{"V2TCP4", v2Header(0x21, 0x11, []byte{192, 168, 0, 1, 192, 168, 0, 11, 0xdc, 0x04, 0x01, 0xbb}), "192.168.0.1:56324"},
+		{"V2Local", v2Header(0x20, 0x00, nil), "pipe"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer server.Close()
+			go func() {
+				defer client.Close()
+				if _, err := client.Write([]byte(c.header + "GET / HTTP/1.1\r\n")); err != nil {
+					t.Errorf("write error: %s", err)
+				}
+			}()
+			conn := newProxyProtoConn(server)
+			if got := conn.RemoteAddr().String(); got != c.wantRemoteAddr {
+				t.Errorf("RemoteAddr wants %s but was %s", c.wantRemoteAddr, got)
+			}
+			b, err := ioutil.ReadAll(conn)
+			if err != nil {
+				t.Fatalf("read error: %s", err)
+			}
+			if w := "GET / HTTP/1.1\r\n"; string(b) != w {
+				t.Errorf("body wants %q but was %q", w, b)
+			}
+		})
+	}
+
+	t.Run("NoHeader", func(t *testing.T) {
+		client, server := net.Pipe()
+		defer server.Close()
+		go func() {
+			defer client.Close()
+			_, _ = client.Write([]byte("GET / HTTP/1.1\r\n"))
+		}()
+		conn := newProxyProtoConn(server)
+		if _, err := conn.Read(make([]byte, 1)); err == nil {
+			t.Errorf("Read wants error but was nil")
+		}
+	})
+}
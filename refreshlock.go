@@ -0,0 +1,121 @@
+package oauth2cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// SingleUseRefreshTokenSafe is an oauth2.TokenSource which shares a token file between processes.
+//
+// If the provider issues single-use refresh tokens (refresh token rotation),
+// multiple processes must not refresh the same token at once.
+// This holds an advisory lock of a file while it reads, refreshes and writes the token,
+// so that only one process at a time uses the refresh token.
+//
+// This is supported on Unix-like platforms.
+type SingleUseRefreshTokenSafe struct {
+	ctx          context.Context
+	oauth2Config *oauth2.Config
+	tokenFile    string
+	lockFile     string
+	mu           sync.Mutex // guards the lock file within the process
+}
+
+// NewSingleUseRefreshTokenSafe returns a SingleUseRefreshTokenSafe which stores the token in the file.
+// The lock file is the token file with ".lock" suffix.
+// The context is used for refreshing the token.
+func NewSingleUseRefreshTokenSafe(ctx context.Context, oauth2Config *oauth2.Config, tokenFile string) *SingleUseRefreshTokenSafe {
+	return &SingleUseRefreshTokenSafe{
+		ctx:          ctx,
+		oauth2Config: oauth2Config,
+		tokenFile:    tokenFile,
+		lockFile:     tokenFile + ".lock",
+	}
+}
+
+// Token returns a valid token.
+// If the token in the file has expired, this refreshes it and writes the new token to the file.
+func (s *SingleUseRefreshTokenSafe) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := lockFile(s.lockFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not lock the file %s: %w", s.lockFile, err)
+	}
+	defer unlock()
+
+	token, err := readTokenFile(s.tokenFile)
+	if err != nil {
+		return nil, err
+	}
+	if token.Valid() {
+		return token, nil
+	}
+	if token.RefreshToken == "" {
+		return nil, fmt.Errorf("token in %s has expired and has no refresh token", s.tokenFile)
+	}
+	refreshed, err := s.oauth2Config.TokenSource(s.ctx, &oauth2.Token{RefreshToken: token.RefreshToken}).Token()
+	if err != nil {
+		return nil, fmt.Errorf("could not refresh the token: %w", err)
+	}
+	if err := writeTokenFile(s.tokenFile, refreshed); err != nil {
+		return nil, err
+	}
+	return refreshed, nil
+}
+
+// Save writes the token to the file, e.g. after the initial authorization.
+func (s *SingleUseRefreshTokenSafe) Save(token *oauth2.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := lockFile(s.lockFile)
+	if err != nil {
+		return fmt.Errorf("could not lock the file %s: %w", s.lockFile, err)
+	}
+	defer unlock()
+	return writeTokenFile(s.tokenFile, token)
+}
+
+func readTokenFile(name string) (*oauth2.Token, error) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the token file: %w", err)
+	}
+	var token oauth2.Token
+	if err := json.Unmarshal(b, &token); err != nil {
+		return nil, fmt.Errorf("invalid token file %s: %w", name, err)
+	}
+	return &token, nil
+}
+
+// writeTokenFile writes the token to a temporary file and renames it,
+// so that a reader never sees a partially written file.
+func writeTokenFile(name string, token *oauth2.Token) error {
+	b, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("could not encode the token: %w", err)
+	}
+	f, err := ioutil.TempFile(filepath.Dir(name), filepath.Base(name)+".tmp")
+	if err != nil {
+		return fmt.Errorf("could not create a temporary file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return fmt.Errorf("could not write the token: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("could not write the token: %w", err)
+	}
+	if err := os.Rename(f.Name(), name); err != nil {
+		return fmt.Errorf("could not write the token file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package oauth2cli
+
+import (
+	"errors"
+	"runtime"
+)
+
+func lockFile(string) (func(), error) {
+	return nil, errors.New("file lock is not supported on " + runtime.GOOS)
+}
@@ -0,0 +1,69 @@
+package oauth2cli
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestSingleUseRefreshTokenSafe(t *testing.T) {
+	// the server accepts each refresh token only once
+	var mu sync.Mutex
+	validRefreshToken, refreshCount := "REFRESH_TOKEN_0", 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if r.FormValue("refresh_token") != validRefreshToken {
+			w.WriteHeader(400)
+			_, _ = fmt.Fprint(w, `{"error":"invalid_grant"}`)
+			return
+		}
+		refreshCount++
+		validRefreshToken = fmt.Sprintf("REFRESH_TOKEN_%d", refreshCount)
+		_, _ = fmt.Fprintf(w, `{"access_token":"ACCESS_TOKEN_%d","token_type":"Bearer","expires_in":3600,"refresh_token":"%s"}`, refreshCount, validRefreshToken)
+	}))
+	defer s.Close()
+
+	dir, err := ioutil.TempDir("", "oauth2cli")
+	if err != nil {
+		t.Fatalf("could not create a temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token.json")
+	oauth2Config := &oauth2.Config{ClientID: "YOUR_CLIENT_ID", Endpoint: oauth2.Endpoint{TokenURL: s.URL}}
+	expired := &oauth2.Token{AccessToken: "EXPIRED", RefreshToken: "REFRESH_TOKEN_0", Expiry: time.Now().Add(-time.Minute)}
+	if err := NewSingleUseRefreshTokenSafe(context.TODO(), oauth2Config, tokenFile).Save(expired); err != nil {
+		t.Fatalf("could not save the token: %s", err)
+	}
+
+	var eg errgroup.Group
+	for i := 0; i < 10; i++ {
+		eg.Go(func() error {
+			token, err := NewSingleUseRefreshTokenSafe(context.TODO(), oauth2Config, tokenFile).Token()
+			if err != nil {
+				return err
+			}
+			if w := "ACCESS_TOKEN_1"; token.AccessToken != w {
+				return fmt.Errorf("AccessToken wants %s but was %s", w, token.AccessToken)
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		t.Fatalf("error: %s", err)
+	}
+	if refreshCount != 1 {
+		t.Errorf("refreshCount wants 1 but was %d", refreshCount)
+	}
+}
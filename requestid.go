@@ -0,0 +1,46 @@
+package oauth2cli
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+type requestIDKey struct{}
+
+// NewRequestIDMiddleware returns a middleware which assigns an ID to each request,
+// e.g. to correlate a log entry with the redirect of the browser.
+// It sets the ID to the X-Request-Id response header and the request context.
+// The local server includes the ID in the logs to Config.Logger.
+// If generator is nil, it defaults to a random UUID.
+//
+// You can set it to Config.LocalServerMiddleware.
+func NewRequestIDMiddleware(generator func() string) func(http.Handler) http.Handler {
+	if generator == nil {
+		generator = newUUID
+	}
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := generator()
+			w.Header().Set("X-Request-Id", id)
+			h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+		})
+	}
+}
+
+// RequestIDFromContext returns the request ID set by NewRequestIDMiddleware.
+// It returns an empty string if not set.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// newUUID returns a random UUID of version 4.
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,94 @@
+package oauth2cli
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestNewRequestIDMiddleware(t *testing.T) {
+	t.Run("Generator", func(t *testing.T) {
+		var got string
+		h := NewRequestIDMiddleware(func() string { return "REQUEST_ID" })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = RequestIDFromContext(r.Context())
+		}))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if got != "REQUEST_ID" {
+			t.Errorf("RequestIDFromContext wants REQUEST_ID but was %s", got)
+		}
+		if got := w.Header().Get("X-Request-Id"); got != "REQUEST_ID" {
+			t.Errorf("X-Request-Id wants REQUEST_ID but was %s", got)
+		}
+	})
+	t.Run("DefaultUUID", func(t *testing.T) {
+		h := NewRequestIDMiddleware(nil)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+		if got := w.Header().Get("X-Request-Id"); !uuid.MatchString(got) {
+			t.Errorf("X-Request-Id wants a UUID but was %s", got)
+		}
+	})
+	t.Run("Logged", func(t *testing.T) {
+		var logs bytes.Buffer
+		h := &localServerHandler{
+			config: &Config{
+				State:                     "STATE",
+				LocalServerSuccessHTML:    DefaultLocalServerSuccessHTML,
+				LocalServerVerboseHeaders: true,
+				Logger:                    slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+			},
+			responseCh: make(chan *authorizationResponse, 1),
+		}
+		m := NewRequestIDMiddleware(func() string { return "REQUEST_ID" })(h)
+		m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/?state=STATE&code=AUTH_CODE", nil))
+		if !strings.Contains(logs.String(), "requestID=REQUEST_ID") {
+			t.Errorf("log wants the request ID but was %s", logs.String())
+		}
+	})
+	t.Run("StateMismatchLogged", func(t *testing.T) {
+		var logs bytes.Buffer
+		h := &localServerHandler{
+			config: &Config{
+				State:  "STATE",
+				Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+			},
+			responseCh: make(chan *authorizationResponse, 1),
+		}
+		m := NewRequestIDMiddleware(func() string { return "REQUEST_ID" })(h)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", "/?state=WRONG&code=AUTH_CODE", nil))
+		if w.Code != 500 {
+			t.Errorf("status wants 500 but was %d", w.Code)
+		}
+		if !strings.Contains(logs.String(), "requestID=REQUEST_ID") {
+			t.Errorf("log wants the request ID but was %s", logs.String())
+		}
+		if !strings.Contains(logs.String(), "state does not match") {
+			t.Errorf("log wants the state mismatch but was %s", logs.String())
+		}
+	})
+	t.Run("NotFoundLogged", func(t *testing.T) {
+		var logs bytes.Buffer
+		h := &localServerHandler{
+			config: &Config{
+				State:  "STATE",
+				Logger: slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+			},
+		}
+		m := NewRequestIDMiddleware(func() string { return "REQUEST_ID" })(h)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", "/favicon.ico", nil))
+		if w.Code != 404 {
+			t.Errorf("status wants 404 but was %d", w.Code)
+		}
+		if !strings.Contains(logs.String(), "requestID=REQUEST_ID") {
+			t.Errorf("log wants the request ID but was %s", logs.String())
+		}
+	})
+}
@@ -1,137 +1,636 @@
 package oauth2cli
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"html/template"
+	"log/slog"
 	"net"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/int128/listener"
 	"golang.org/x/sync/errgroup"
 )
 
-func receiveCodeViaLocalServer(ctx context.Context, c *Config) (string, error) {
+func receiveCodeViaLocalServer(ctx context.Context, c *Config) (*authorizationResponse, error) {
 	l, err := listener.New(c.LocalServerBindAddress)
 	if err != nil {
-		return "", fmt.Errorf("could not start a local server: %w", err)
+		return nil, fmt.Errorf("could not start a local server: %w", err)
 	}
 	defer l.Close()
 	c.OAuth2Config.RedirectURL = computeRedirectURL(l, c)
+	if c.TunnelProvider != nil {
+		publicURL, err := c.TunnelProvider.Start(ctx, l.Addr().String())
+		if err != nil {
+			return nil, fmt.Errorf("could not start a tunnel: %w", err)
+		}
+		defer func() { _ = c.TunnelProvider.Stop() }()
+		c.OAuth2Config.RedirectURL = publicURL
+		if c.redirectPath != "" {
+			c.OAuth2Config.RedirectURL = strings.TrimSuffix(publicURL, "/") + c.redirectPath
+		}
+	}
+	authCodeURL, err := prepareAuthorizationURL(c, l.Addr())
+	if err != nil {
+		return nil, err
+	}
 
-	respCh := make(chan *authorizationResponse)
-	server := http.Server{
-		Handler: c.LocalServerMiddleware(&localServerHandler{
-			config:     c,
-			responseCh: respCh,
-		}),
+	// the handler sends only the first response without blocking
+	respCh := make(chan *authorizationResponse, 1)
+	var handler http.Handler = c.LocalServerMiddleware(corsMiddleware(c, &localServerHandler{
+		config:     c,
+		responseCh: respCh,
+	}))
+	if c.LocalServerRequestTimeout > 0 {
+		handler = requestTimeoutHandler(c, handler, c.LocalServerRequestTimeout)
+	}
+	server := http.Server{Handler: handler}
+	if c.LocalServerClientCertValidator != nil {
+		// the handler verifies the certificate by the validator
+		server.TLSConfig = &tls.Config{ClientAuth: tls.RequestClientCert}
+	}
+	var serverListener net.Listener = l
+	if c.LocalServerTCPKeepAlive != 0 {
+		serverListener = &keepAliveListener{Listener: serverListener, period: c.LocalServerTCPKeepAlive}
+	}
+	if c.LocalServerAcceptTimeout > 0 {
+		serverListener = newAcceptTimeoutListener(ctx, serverListener, c.LocalServerAcceptTimeout, c.logger())
+	}
+	if c.LocalServerProxyProto {
+		serverListener = &proxyProtoListener{Listener: serverListener}
 	}
+	// requests to the local server inherit the values of the context, such as a trace span
+	server.BaseContext = func(net.Listener) context.Context { return ctx }
 	var resp *authorizationResponse
-	var eg errgroup.Group
+	eg, egCtx := errgroup.WithContext(ctx)
 	eg.Go(func() error {
-		for {
-			select {
-			case received, ok := <-respCh:
-				if !ok {
-					return nil // channel is closed (after the server is stopped)
-				}
-				if resp == nil {
-					resp = received // pick only the first response
-				}
-				if err := server.Shutdown(ctx); err != nil {
-					return fmt.Errorf("could not shutdown the local server: %w", err)
-				}
-			case <-ctx.Done():
-				if err := server.Shutdown(ctx); err != nil {
-					return fmt.Errorf("could not shutdown the local server: %w", err)
-				}
-				return fmt.Errorf("context done while waiting for authorization response: %w", ctx.Err())
+		select {
+		case resp = <-respCh:
+			if c.LocalServerEarlyClose {
+				// close the connections without waiting for the response to be sent
+				_ = server.Close()
+				return nil
 			}
+			if err := shutdownLocalServer(ctx, &server); err != nil {
+				return err
+			}
+			return nil
+		case <-egCtx.Done():
+			if err := shutdownLocalServer(ctx, &server); err != nil {
+				return err
+			}
+			return fmt.Errorf("context done while waiting for authorization response: %w", egCtx.Err())
 		}
 	})
 	eg.Go(func() error {
-		defer close(respCh)
 		if c.LocalServerCertFile != "" && c.LocalServerKeyFile != "" {
-			if err := server.ServeTLS(l, c.LocalServerCertFile, c.LocalServerKeyFile); err != nil && err != http.ErrServerClosed {
+			if err := server.ServeTLS(serverListener, c.LocalServerCertFile, c.LocalServerKeyFile); err != nil && err != http.ErrServerClosed {
 				return fmt.Errorf("could not start a local TLS server: %w", err)
 			}
 		} else {
-			if err := server.Serve(l); err != nil && err != http.ErrServerClosed {
+			if err := server.Serve(serverListener); err != nil && err != http.ErrServerClosed {
 				return fmt.Errorf("could not start a local server: %w", err)
 			}
 		}
 		return nil
 	})
+	if c.CopyAuthURLToClipboard {
+		// the listener is up, so the user can open the authorization URL at any time
+		go copyAuthURLToClipboard(authCodeURL)
+	}
 	if c.LocalServerReadyChan != nil {
 		c.LocalServerReadyChan <- c.OAuth2Config.RedirectURL
 	}
+	if c.BrowserOpener != nil {
+		go func(u string) {
+			if err := c.BrowserOpener.OpenURL(u); err != nil {
+				c.logger().Warn("could not open the browser", "error", err)
+			}
+		}(c.OAuth2Config.RedirectURL)
+	}
 
 	if err := eg.Wait(); err != nil {
-		return "", fmt.Errorf("authorization error: %w", err)
+		return nil, fmt.Errorf("authorization error: %w", err)
 	}
 	if resp == nil {
-		return "", errors.New("no authorization response")
+		return nil, errors.New("no authorization response")
+	}
+	if resp.err != nil {
+		return nil, resp.err
+	}
+	return resp, nil
+}
+
+// requestTimeoutHandler returns a handler which sets the deadline to the context of each request.
+// If the handler returns without a response after the deadline, it responds 503.
+// Unlike http.TimeoutHandler, the response is not buffered and can be flushed.
+func requestTimeoutHandler(c *Config, h http.Handler, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		tw := &timeoutResponseWriter{ResponseWriter: w}
+		h.ServeHTTP(tw, r.WithContext(ctx))
+		if !tw.wrote && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			c.requestLogger(r).Warn("request to the local server timed out",
+				"timeout", timeout, "remoteAddr", r.RemoteAddr)
+			http.Error(w, "local server timed out", 503)
+		}
+	})
+}
+
+// timeoutResponseWriter records whether the handler has written the response.
+type timeoutResponseWriter struct {
+	http.ResponseWriter
+	wrote bool
+}
+
+func (w *timeoutResponseWriter) WriteHeader(statusCode int) {
+	w.wrote = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *timeoutResponseWriter) Write(b []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *timeoutResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wrote = true
+		f.Flush()
 	}
-	return resp.code, resp.err
+}
+
+func (w *timeoutResponseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+const localServerShutdownTimeout = 3 * time.Second
+
+// shutdownLocalServer gracefully shuts down the server.
+// It keeps the values of the context but not the cancellation,
+// because the context may have been canceled already.
+func shutdownLocalServer(ctx context.Context, server *http.Server) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), localServerShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("could not shutdown the local server: %w", err)
+	}
+	return nil
 }
 
 func computeRedirectURL(l net.Listener, c *Config) string {
-	hostPort := fmt.Sprintf("%s:%d", c.RedirectURLHostname, l.Addr().(*net.TCPAddr).Port)
-	if c.LocalServerCertFile != "" {
-		return "https://" + hostPort
+	port := strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+	useTLS := c.LocalServerCertFile != ""
+	if c.LocalServerScheme != "" {
+		useTLS = c.LocalServerScheme == "https"
+	}
+	return buildURL(c.RedirectURLHostname, port, c.redirectPath, useTLS)
+}
+
+// BuildRedirectURL returns the URL of a local server which listens on the address.
+// This is useful if you start your own local server and need to build the redirect URL.
+//
+// The host is enclosed in brackets if it is an IPv6 address.
+// If the address is unspecified (such as 0.0.0.0 or ::), localhost is used instead.
+// The path may be empty.
+func BuildRedirectURL(addr net.Addr, path string, useTLS bool) (string, error) {
+	if addr == nil {
+		return "", errors.New("address is nil")
+	}
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return "", fmt.Errorf("invalid address %s: %w", addr, err)
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = "localhost"
+	}
+	return buildURL(host, port, path, useTLS), nil
+}
+
+func buildURL(host, port, path string, useTLS bool) string {
+	u := url.URL{Scheme: "http", Host: net.JoinHostPort(host, port), Path: path}
+	if useTLS {
+		u.Scheme = "https"
+	}
+	if path != "" && !strings.HasPrefix(path, "/") {
+		u.Path = "/" + path
+	}
+	return u.String()
+}
+
+// prepareAuthorizationURL notifies that the receiver is started at addr,
+// applies Config.OAuth2ConfigMutator, signs and validates the authorization request,
+// and then returns the authorization URL.
+// It is shared by the local server and the named pipe.
+func prepareAuthorizationURL(c *Config, addr net.Addr) (string, error) {
+	c.stats.localServerStarted(addr.String())
+	if c.LocalServerOnStarted != nil {
+		c.LocalServerOnStarted(addr)
+	}
+	if c.OAuth2ConfigMutator != nil {
+		c.OAuth2ConfigMutator(&c.OAuth2Config)
+	}
+	if c.authCodeOptionsSigner != nil {
+		if err := signAuthCodeOptions(c); err != nil {
+			return "", err
+		}
+	}
+	if c.AuthorizationURLValidator != nil {
+		if err := validateAuthorizationURL(c); err != nil {
+			return "", err
+		}
+	}
+	return c.OAuth2Config.AuthCodeURL(c.State, c.AuthCodeOptions...), nil
+}
+
+// signAuthCodeOptions appends the options returned by the signer to a copy of AuthCodeOptions.
+func signAuthCodeOptions(c *Config) error {
+	u, err := url.Parse(c.OAuth2Config.AuthCodeURL(c.State, c.AuthCodeOptions...))
+	if err != nil {
+		return fmt.Errorf("invalid authorization URL: %w", err)
+	}
+	opts, err := c.authCodeOptionsSigner(u.Query())
+	if err != nil {
+		return fmt.Errorf("could not sign the authorization request: %w", err)
+	}
+	c.appendAuthCodeOptions(opts...)
+	return nil
+}
+
+func validateAuthorizationURL(c *Config) error {
+	u, err := url.Parse(c.OAuth2Config.AuthCodeURL(c.State, c.AuthCodeOptions...))
+	if err != nil {
+		return fmt.Errorf("invalid authorization URL: %w", err)
+	}
+	if err := c.AuthorizationURLValidator(u); err != nil {
+		return fmt.Errorf("authorization URL is rejected: %w", err)
+	}
+	return nil
+}
+
+func (h *localServerHandler) writeErrorHTML(w http.ResponseWriter, r *http.Request, status int, errorCode, errorDescription string) {
+	errorHTML := h.config.LocalServerErrorHTML
+	if errorHTML == "" {
+		errorHTML = DefaultLocalServerErrorHTML
+	}
+	if h.config.LocalServerSuppressWindowClose {
+		errorHTML = strings.Replace(errorHTML, windowCloseScript, "", -1)
+	}
+	tpl, err := template.New("error").Parse(errorHTML)
+	if err != nil {
+		h.config.requestLogger(r).Error("invalid template of LocalServerErrorHTML", "error", err)
+		http.Error(w, "authorization error", 500)
+		return
+	}
+	var b bytes.Buffer
+	data := struct{ ErrorCode, ErrorDescription string }{errorCode, errorDescription}
+	if err := tpl.Execute(&b, data); err != nil {
+		h.config.requestLogger(r).Error("could not render LocalServerErrorHTML", "error", err)
+		http.Error(w, "authorization error", 500)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(status)
+	_, _ = w.Write(b.Bytes())
+}
+
+// acceptsJSON returns true if the Accept header has application/json.
+func acceptsJSON(r *http.Request) bool {
+	for _, v := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(v, ",") {
+			mediaType := strings.TrimSpace(strings.SplitN(mediaRange, ";", 2)[0])
+			if strings.EqualFold(mediaType, "application/json") {
+				return true
+			}
+		}
 	}
-	return "http://" + hostPort
+	return false
 }
 
+const windowCloseScript = "<script>window.close()</script>"
+
 type authorizationResponse struct {
-	code string // non-empty if a valid code is received
-	err  error  // non-nil if an error is received or any error occurs
+	code    string // non-empty if a valid code is received
+	idToken string // non-empty if an ID token is received in the hybrid flow
+	err     error  // non-nil if an error is received or any error occurs
 }
 
 type localServerHandler struct {
 	config     *Config
 	responseCh chan<- *authorizationResponse
+	used       int32        // set to 1 when a valid response is received
+	firstCode  atomic.Value // code of the first valid response
+
+	redirectHandlerOnce sync.Once
+	redirect            http.Handler
+}
+
+// sendResponse sends the response to the receiver.
+// If a response has already been sent, this discards the subsequent ones.
+func (h *localServerHandler) sendResponse(resp *authorizationResponse) {
+	select {
+	case h.responseCh <- resp:
+	default:
+	}
 }
 
 func (h *localServerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if limit := h.config.LocalServerResponseBodyLimit; limit > 0 {
+		if r.ContentLength > limit {
+			h.config.requestLogger(r).Warn("request body exceeds the limit",
+				"contentLength", r.ContentLength, "limit", limit, "remoteAddr", r.RemoteAddr)
+			http.Error(w, "payload too large", 413)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+	}
 	q := r.URL.Query()
+	path := h.config.localServerPath()
 	switch {
-	case r.Method == "GET" && r.URL.Path == "/" && q.Get("error") != "":
-		h.responseCh <- h.handleErrorResponse(w, r)
-	case r.Method == "GET" && r.URL.Path == "/" && q.Get("code") != "":
-		h.responseCh <- h.handleCodeResponse(w, r)
-	case r.Method == "GET" && r.URL.Path == "/":
+	case r.Method == "GET" && r.URL.Path == path && (q.Get("error") != "" || q.Get("code") != ""):
+		h.redirectHandler().ServeHTTP(w, r)
+	case r.Method == "POST" && r.URL.Path == path && h.config.HybridResponseType != "":
+		h.handleHybridPost(w, r)
+	case r.Method == "GET" && r.URL.Path == path && h.config.HybridResponseType != "":
+		h.handleHybridIndex(w, r)
+	case r.Method == "GET" && r.URL.Path == path:
 		h.handleIndex(w, r)
 	default:
+		// a browser may request other paths, such as /favicon.ico
+		h.config.requestLogger(r).Debug("no handler for the request to the local server",
+			"method", r.Method, "path", r.URL.Path, "remoteAddr", r.RemoteAddr)
 		http.NotFound(w, r)
 	}
 }
 
+// redirectHandler returns the handler of the authorization response,
+// wrapped by LocalServerRedirectMiddleware if it is set.
+func (h *localServerHandler) redirectHandler() http.Handler {
+	h.redirectHandlerOnce.Do(func() {
+		h.redirect = http.HandlerFunc(h.serveRedirect)
+		if h.config.LocalServerRedirectMiddleware != nil {
+			h.redirect = h.config.LocalServerRedirectMiddleware(h.redirect)
+		}
+	})
+	return h.redirect
+}
+
+func (h *localServerHandler) serveRedirect(w http.ResponseWriter, r *http.Request) {
+	if h.config.LocalServerVerboseHeaders && h.config.Logger != nil {
+		h.config.requestLogger(r).Debug("received a redirect to the local server",
+			"remoteAddr", r.RemoteAddr, headersAttr(r.Header))
+	}
+	if !h.verifyClientCert(w, r) {
+		return
+	}
+	if r.Context().Err() != nil {
+		// the flow has been canceled, e.g. by a signal
+		h.config.requestLogger(r).Warn("received a redirect after the authorization was canceled",
+			"remoteAddr", r.RemoteAddr)
+		h.writeErrorHTML(w, r, 503, "canceled", "the authorization was canceled")
+		return
+	}
+	if h.config.isLocalServerSingleUse() && atomic.LoadInt32(&h.used) == 1 {
+		h.handleDuplicateResponse(w, r)
+		return
+	}
+	if r.URL.Query().Get("error") != "" {
+		h.sendResponse(h.handleErrorResponse(w, r))
+		return
+	}
+	if resp := h.handleCodeResponse(w, r); resp != nil {
+		h.sendResponse(resp)
+	}
+}
+
+// headersAttr returns an attribute of the headers with the credentials redacted.
+func headersAttr(header http.Header) slog.Attr {
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]any, 0, len(keys))
+	for _, k := range keys {
+		v := strings.Join(header[k], ", ")
+		switch http.CanonicalHeaderKey(k) {
+		case "Authorization", "Cookie", "Proxy-Authorization":
+			v = "REDACTED"
+		}
+		attrs = append(attrs, slog.String(k, v))
+	}
+	return slog.Group("headers", attrs...)
+}
+
+// verifyClientCert calls the validator with the client certificate if it is set.
+// It returns false if the request is rejected.
+func (h *localServerHandler) verifyClientCert(w http.ResponseWriter, r *http.Request) bool {
+	if h.config.LocalServerClientCertValidator == nil {
+		return true
+	}
+	var cert *x509.Certificate
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		cert = r.TLS.PeerCertificates[0]
+	}
+	if err := h.config.LocalServerClientCertValidator(cert); err != nil {
+		h.config.requestLogger(r).Warn("client certificate is rejected",
+			"error", err, "remoteAddr", r.RemoteAddr)
+		http.Error(w, "forbidden", 403)
+		return false
+	}
+	return true
+}
+
 func (h *localServerHandler) handleIndex(w http.ResponseWriter, r *http.Request) {
+	h.config.stats.browserOpened()
 	authCodeURL := h.config.OAuth2Config.AuthCodeURL(h.config.State, h.config.AuthCodeOptions...)
+	if !h.config.authorizationDeadline.IsZero() {
+		h.writeTimeoutHTML(w, r, authCodeURL)
+		return
+	}
 	http.Redirect(w, r, authCodeURL, 302)
 }
 
+// writeTimeoutHTML writes the page with the remaining time until Config.AuthorizationTimeout.
+func (h *localServerHandler) writeTimeoutHTML(w http.ResponseWriter, r *http.Request, authCodeURL string) {
+	tpl, err := template.New("timeout").Parse(h.config.LocalServerTimeoutHTML)
+	if err != nil {
+		h.config.requestLogger(r).Error("invalid template of LocalServerTimeoutHTML", "error", err)
+		http.Error(w, "server error", 500)
+		return
+	}
+	remaining, remainingSeconds := h.authorizationRemaining()
+	var b bytes.Buffer
+	data := struct {
+		AuthCodeURL      string
+		Remaining        string
+		RemainingSeconds int
+	}{authCodeURL, remaining, remainingSeconds}
+	if err := tpl.Execute(&b, data); err != nil {
+		h.config.requestLogger(r).Error("could not render LocalServerTimeoutHTML", "error", err)
+		http.Error(w, "server error", 500)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	_, _ = w.Write(b.Bytes())
+}
+
+// authorizationRemaining returns the remaining time until Config.AuthorizationTimeout,
+// in the form of 4:59 and seconds.
+func (h *localServerHandler) authorizationRemaining() (string, int) {
+	remaining := int(time.Until(h.config.authorizationDeadline).Seconds())
+	if remaining < 0 {
+		remaining = 0
+	}
+	return fmt.Sprintf("%d:%02d", remaining/60, remaining%60), remaining
+}
+
+// handleCodeResponse returns the response to send to the receiver.
+// It returns nil if the response should be discarded.
 func (h *localServerHandler) handleCodeResponse(w http.ResponseWriter, r *http.Request) *authorizationResponse {
 	q := r.URL.Query()
-	code, state := q.Get("code"), q.Get("state")
+	code := q.Get("code")
+	h.config.delayResponseForTesting(r)
 
-	if state != h.config.State {
+	if err := h.config.validateAuthorizationResponse(q); err != nil {
+		h.config.requestLogger(r).Warn("authorization response is rejected",
+			"error", err, "remoteAddr", r.RemoteAddr)
 		http.Error(w, "authorization error", 500)
-		return &authorizationResponse{err: fmt.Errorf("state does not match (wants %s but got %s)", h.config.State, state)}
+		return &authorizationResponse{err: err}
 	}
-	w.Header().Add("Content-Type", "text/html")
-	if _, err := fmt.Fprintf(w, h.config.LocalServerSuccessHTML); err != nil {
+	if h.config.isLocalServerSingleUse() {
+		if !atomic.CompareAndSwapInt32(&h.used, 0, 1) {
+			// another redirect has won the race
+			h.handleDuplicateResponse(w, r)
+			return nil
+		}
+		h.firstCode.Store(code)
+	}
+	h.config.stats.codeReceived()
+	if h.config.LocalServerEarlyClose {
+		w.Header().Set("Connection", "close")
+		w.WriteHeader(200)
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		return &authorizationResponse{code: code, idToken: q.Get("id_token")}
+	}
+	if err := h.writeSuccess(w, r, code); err != nil {
+		h.config.requestLogger(r).Warn("could not write the response", "error", err)
 		http.Error(w, "server error", 500)
 		return &authorizationResponse{err: fmt.Errorf("write error: %w", err)}
 	}
-	return &authorizationResponse{code: code}
+	return &authorizationResponse{code: code, idToken: q.Get("id_token")}
+}
+
+// validateAuthorizationResponse validates the parameters of the authorization response.
+// It is shared by the local server and the named pipe.
+func (c *Config) validateAuthorizationResponse(q url.Values) error {
+	state := q.Get("state")
+	if c.StateMaxLength > 0 && len(state) > c.StateMaxLength {
+		return fmt.Errorf("state has %d characters which exceeds StateMaxLength %d", len(state), c.StateMaxLength)
+	}
+	if state != c.State {
+		return fmt.Errorf("state does not match (wants %s but got %s)", c.State, state)
+	}
+	if q.Get("code") == "" {
+		return errors.New("no code in the authorization response")
+	}
+	if sessionState := q.Get("session_state"); sessionState != "" && c.SessionStateValidator != nil {
+		if err := c.SessionStateValidator(sessionState); err != nil {
+			return fmt.Errorf("invalid session_state: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeSuccess writes the success HTML,
+// or a JSON if the client accepts it, e.g. fetch() of an IDE extension.
+// If the code is empty, it is omitted from the JSON response.
+func (h *localServerHandler) writeSuccess(w http.ResponseWriter, r *http.Request, code string) error {
+	if acceptsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		return json.NewEncoder(w).Encode(struct {
+			Status string `json:"status"`
+			Code   string `json:"code,omitempty"`
+		}{"ok", code})
+	}
+	successHTML := h.config.LocalServerSuccessHTML
+	if h.config.LocalServerSuppressWindowClose {
+		successHTML = strings.Replace(successHTML, windowCloseScript, "", -1)
+	}
+	w.Header().Add("Content-Type", "text/html")
+	_, err := fmt.Fprintf(w, successHTML)
+	return err
+}
+
+// handleDuplicateResponse responds to a redirect after the first valid response,
+// e.g. the authorization URL was opened in two browser tabs.
+// The first code is always used, and it is never sent to the subsequent redirects.
+func (h *localServerHandler) handleDuplicateResponse(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	firstCode, _ := h.firstCode.Load().(string)
+	sameState := q.Get("state") == h.config.State
+	h.config.requestLogger(r).Warn("received a duplicate authorization response",
+		"sameState", sameState,
+		"sameCode", q.Get("code") == firstCode,
+		"remoteAddr", r.RemoteAddr)
+	if h.config.AllowDuplicateState && sameState && q.Get("error") == "" {
+		_ = h.writeSuccess(w, r, "")
+		return
+	}
+	h.writeAlreadyUsed(w, r)
+}
+
+func (h *localServerHandler) writeAlreadyUsed(w http.ResponseWriter, r *http.Request) {
+	if acceptsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(410)
+		_ = json.NewEncoder(w).Encode(struct {
+			Status string `json:"status"`
+			Error  string `json:"error"`
+		}{"error", "already_used"})
+		return
+	}
+	alreadyUsedHTML := h.config.LocalServerAlreadyUsedHTML
+	if alreadyUsedHTML == "" {
+		alreadyUsedHTML = DefaultLocalServerAlreadyUsedHTML
+	}
+	if h.config.LocalServerSuppressWindowClose {
+		alreadyUsedHTML = strings.Replace(alreadyUsedHTML, windowCloseScript, "", -1)
+	}
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(410)
+	_, _ = fmt.Fprint(w, alreadyUsedHTML)
 }
 
 func (h *localServerHandler) handleErrorResponse(w http.ResponseWriter, r *http.Request) *authorizationResponse {
 	q := r.URL.Query()
 	errorCode, errorDescription := q.Get("error"), q.Get("error_description")
+	h.config.requestLogger(r).Warn("received an authorization error",
+		"error", errorCode, "errorDescription", errorDescription, "remoteAddr", r.RemoteAddr)
 
-	http.Error(w, "authorization error", 500)
+	if acceptsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(500)
+		_ = json.NewEncoder(w).Encode(struct {
+			Status           string `json:"status"`
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description,omitempty"`
+		}{"error", errorCode, errorDescription})
+	} else {
+		h.writeErrorHTML(w, r, 500, errorCode, errorDescription)
+	}
 	return &authorizationResponse{err: fmt.Errorf("authorization error from server: %s %s", errorCode, errorDescription)}
 }
@@ -0,0 +1,748 @@
+package oauth2cli
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestBuildRedirectURL(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		addr   net.Addr
+		path   string
+		useTLS bool
+		want   string
+	}{
+		{"IPv4", &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8000}, "", false, "http://127.0.0.1:8000"},
+		{"IPv4WithPath", &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8000}, "/callback", false, "http://127.0.0.1:8000/callback"},
+		{"PathWithoutSlash", &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8000}, "callback", false, "http://127.0.0.1:8000/callback"},
+		{"IPv6", &net.TCPAddr{IP: net.ParseIP("::1"), Port: 8000}, "", false, "http://[::1]:8000"},
+		{"IPv6WithZone", &net.TCPAddr{IP: net.ParseIP("fe80::1"), Port: 8000, Zone: "eth0"}, "", false, "http://[fe80::1%25eth0]:8000"},
+		{"TLS", &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8443}, "/", true, "https://127.0.0.1:8443/"},
+		{"UnspecifiedIPv4", &net.TCPAddr{IP: net.ParseIP("0.0.0.0"), Port: 8000}, "", false, "http://localhost:8000"},
+		{"UnspecifiedIPv6", &net.TCPAddr{IP: net.ParseIP("::"), Port: 8000}, "", false, "http://localhost:8000"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := BuildRedirectURL(c.addr, c.path, c.useTLS)
+			if err != nil {
+				t.Fatalf("BuildRedirectURL error: %s", err)
+			}
+			if got != c.want {
+				t.Errorf("wants %s but was %s", c.want, got)
+			}
+		})
+	}
+
+	t.Run("InvalidAddress", func(t *testing.T) {
+		_, err := BuildRedirectURL(&net.UnixAddr{Name: "/tmp/socket", Net: "unix"}, "", false)
+		if err == nil {
+			t.Errorf("BuildRedirectURL wants error but was nil")
+		}
+	})
+}
+
+func TestComputeRedirectURL(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %s", err)
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+	for _, c := range []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{"Default", Config{RedirectURLHostname: "localhost"}, fmt.Sprintf("http://localhost:%d", port)},
+		{"CertFile", Config{RedirectURLHostname: "localhost", LocalServerCertFile: "cert.pem"}, fmt.Sprintf("https://localhost:%d", port)},
+		{"SchemeHTTPS", Config{RedirectURLHostname: "localhost", LocalServerScheme: "https"}, fmt.Sprintf("https://localhost:%d", port)},
+		{"SchemeHTTPWithCertFile", Config{RedirectURLHostname: "localhost", LocalServerCertFile: "cert.pem", LocalServerScheme: "http"}, fmt.Sprintf("http://localhost:%d", port)},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if got := computeRedirectURL(l, &c.cfg); got != c.want {
+				t.Errorf("wants %s but was %s", c.want, got)
+			}
+		})
+	}
+
+	t.Run("InvalidScheme", func(t *testing.T) {
+		cfg := Config{LocalServerScheme: "ftp"}
+		if err := cfg.validateAndSetDefaults(); err == nil {
+			t.Errorf("validateAndSetDefaults wants error but was nil")
+		}
+	})
+}
+
+func TestLocalServerHandler_ClientCertValidator(t *testing.T) {
+	h := &localServerHandler{
+		config: &Config{
+			State: "STATE",
+			LocalServerClientCertValidator: func(cert *x509.Certificate) error {
+				if cert == nil {
+					return errors.New("no client certificate")
+				}
+				return nil
+			},
+		},
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/?state=STATE&code=AUTH_CODE", nil))
+	if w.Code != 403 {
+		t.Errorf("status wants 403 but was %d", w.Code)
+	}
+}
+
+func TestLocalServerHandler_SingleUse(t *testing.T) {
+	for _, c := range []struct {
+		name       string
+		singleUse  *bool
+		wantStatus int
+	}{
+		{"Default", nil, 410},
+		{"True", boolPtr(true), 410},
+		{"False", boolPtr(false), 200},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			h := &localServerHandler{
+				config: &Config{State: "STATE", LocalServerSingleUse: c.singleUse},
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", "/?state=STATE&code=AUTH_CODE", nil))
+			if w.Code != 200 {
+				t.Errorf("first status wants 200 but was %d", w.Code)
+			}
+			w = httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", "/?state=STATE&code=ANOTHER_CODE", nil))
+			if w.Code != c.wantStatus {
+				t.Errorf("second status wants %d but was %d", c.wantStatus, w.Code)
+			}
+		})
+	}
+
+	t.Run("InvalidStateDoesNotUse", func(t *testing.T) {
+		h := &localServerHandler{config: &Config{State: "STATE"}}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/?state=WRONG&code=AUTH_CODE", nil))
+		if w.Code != 500 {
+			t.Errorf("first status wants 500 but was %d", w.Code)
+		}
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/?state=STATE&code=AUTH_CODE", nil))
+		if w.Code != 200 {
+			t.Errorf("second status wants 200 but was %d", w.Code)
+		}
+	})
+}
+
+func TestLocalServerHandler_DuplicateResponse(t *testing.T) {
+	for _, c := range []struct {
+		name                string
+		allowDuplicateState bool
+		target              string
+		accept              string
+		wantStatus          int
+	}{
+		{"Reject", false, "/?state=STATE&code=ANOTHER_CODE", "", 410},
+		{"Allow", true, "/?state=STATE&code=ANOTHER_CODE", "", 200},
+		{"AllowJSON", true, "/?state=STATE&code=ANOTHER_CODE", "application/json", 200},
+		{"AllowButWrongState", true, "/?state=WRONG&code=ANOTHER_CODE", "", 410},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			respCh := make(chan *authorizationResponse, 1)
+			h := &localServerHandler{
+				config: &Config{
+					State:                  "STATE",
+					AllowDuplicateState:    c.allowDuplicateState,
+					LocalServerSuccessHTML: DefaultLocalServerSuccessHTML,
+					Logger:                 slog.New(slog.NewTextHandler(&logs, nil)),
+				},
+				responseCh: respCh,
+			}
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/?state=STATE&code=AUTH_CODE", nil))
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", c.target, nil)
+			if c.accept != "" {
+				r.Header.Set("Accept", c.accept)
+			}
+			h.ServeHTTP(w, r)
+			if w.Code != c.wantStatus {
+				t.Errorf("second status wants %d but was %d", c.wantStatus, w.Code)
+			}
+			if strings.Contains(w.Body.String(), "AUTH_CODE") {
+				t.Errorf("second response wants no code but was %s", w.Body.String())
+			}
+			if !strings.Contains(logs.String(), "duplicate authorization response") {
+				t.Errorf("log wants the duplicate response but was %s", logs.String())
+			}
+			if resp := <-respCh; resp.code != "AUTH_CODE" {
+				t.Errorf("code wants the first code but was %s", resp.code)
+			}
+		})
+	}
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestLocalServerHandler_SuppressWindowClose(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		suppress bool
+		want     string
+	}{
+		{"Default", false, DefaultLocalServerSuccessHTML},
+		{"Suppress", true, `<html><body>OK</body></html>`},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			h := &localServerHandler{
+				config: &Config{
+					State:                          "STATE",
+					LocalServerSuccessHTML:         DefaultLocalServerSuccessHTML,
+					LocalServerSuppressWindowClose: c.suppress,
+				},
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", "/?state=STATE&code=AUTH_CODE", nil))
+			if got := w.Body.String(); got != c.want {
+				t.Errorf("body wants %s but was %s", c.want, got)
+			}
+		})
+	}
+}
+
+func TestLocalServerHandler_EarlyClose(t *testing.T) {
+	respCh := make(chan *authorizationResponse, 1)
+	h := &localServerHandler{
+		config: &Config{
+			State:                  "STATE",
+			LocalServerSuccessHTML: DefaultLocalServerSuccessHTML,
+			LocalServerEarlyClose:  true,
+		},
+		responseCh: respCh,
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/?state=STATE&code=AUTH_CODE", nil))
+	if w.Code != 200 {
+		t.Errorf("status wants 200 but was %d", w.Code)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("body wants empty but was %s", w.Body.String())
+	}
+	if got := w.Header().Get("Connection"); got != "close" {
+		t.Errorf("Connection header wants close but was %s", got)
+	}
+	if resp := <-respCh; resp.code != "AUTH_CODE" {
+		t.Errorf("code wants AUTH_CODE but was %s", resp.code)
+	}
+}
+
+func TestLocalServerHandler_AuthorizationTimeout(t *testing.T) {
+	cfg := &Config{
+		State:                 "STATE",
+		OAuth2Config:          oauth2.Config{Endpoint: oauth2.Endpoint{AuthURL: "https://example.com/auth"}},
+		authorizationDeadline: time.Now().Add(5 * time.Minute),
+	}
+	if err := cfg.validateAndSetDefaults(); err != nil {
+		t.Fatalf("validateAndSetDefaults error: %s", err)
+	}
+	h := &localServerHandler{config: cfg}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != 200 {
+		t.Errorf("status wants 200 but was %d", w.Code)
+	}
+	got := w.Body.String()
+	for _, want := range []string{`data-timeout="299"`, `Time remaining: <span id="remaining">4:59</span>`, "setInterval", `<meta http-equiv="refresh" content="2;url=https://example.com/auth?`, `href="https://example.com/auth?`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("body wants %s but was %s", want, got)
+		}
+	}
+}
+
+func TestLocalServerHandler_Canceled(t *testing.T) {
+	h := &localServerHandler{config: &Config{State: "STATE", LocalServerErrorHTML: DefaultLocalServerErrorHTML}}
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/?state=STATE&code=AUTH_CODE", nil).WithContext(ctx))
+	if w.Code != 503 {
+		t.Errorf("status wants 503 but was %d", w.Code)
+	}
+	if got := w.Body.String(); !strings.Contains(got, "the authorization was canceled") {
+		t.Errorf("body wants the cancellation but was %s", got)
+	}
+}
+
+func TestReceiveCodeViaLocalServer_Context(t *testing.T) {
+	type contextKey struct{}
+	ctx, cancel := context.WithTimeout(context.WithValue(context.TODO(), contextKey{}, "VALUE"), 1*time.Second)
+	defer cancel()
+	readyCh := make(chan string, 1)
+	cfg := Config{
+		State: "STATE",
+		LocalServerMiddleware: func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Context().Value(contextKey{}); got != "VALUE" {
+					t.Errorf("context value wants VALUE but was %v", got)
+				}
+				h.ServeHTTP(w, r)
+			})
+		},
+		LocalServerReadyChan: readyCh,
+	}
+	if err := cfg.validateAndSetDefaults(); err != nil {
+		t.Fatalf("validateAndSetDefaults error: %s", err)
+	}
+	go func() {
+		resp, err := http.Get(<-readyCh + "/?state=STATE&code=AUTH_CODE")
+		if err != nil {
+			t.Errorf("could not send a request: %s", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+	resp, err := receiveCodeViaLocalServer(ctx, &cfg)
+	if err != nil {
+		t.Fatalf("receiveCodeViaLocalServer error: %s", err)
+	}
+	if w := "AUTH_CODE"; resp.code != w {
+		t.Errorf("code wants %s but was %s", w, resp.code)
+	}
+}
+
+func TestReceiveCodeViaLocalServer_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	readyCh := make(chan string, 1)
+	cfg := Config{LocalServerReadyChan: readyCh}
+	if err := cfg.validateAndSetDefaults(); err != nil {
+		t.Fatalf("validateAndSetDefaults error: %s", err)
+	}
+	go func() {
+		<-readyCh
+		cancel()
+	}()
+	_, err := receiveCodeViaLocalServer(ctx, &cfg)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error wants context.Canceled but was %v", err)
+	}
+}
+
+func TestReceiveCodeViaLocalServer_AcceptTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+	defer cancel()
+	readyCh := make(chan string, 1)
+	cfg := Config{
+		State:                    "STATE",
+		LocalServerAcceptTimeout: 10 * time.Millisecond,
+		LocalServerReadyChan:     readyCh,
+	}
+	if err := cfg.validateAndSetDefaults(); err != nil {
+		t.Fatalf("validateAndSetDefaults error: %s", err)
+	}
+	go func() {
+		u := <-readyCh
+		// wait for some timeouts before the redirect
+		time.Sleep(50 * time.Millisecond)
+		resp, err := http.Get(u + "/?state=STATE&code=AUTH_CODE")
+		if err != nil {
+			t.Errorf("could not send a request: %s", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+	resp, err := receiveCodeViaLocalServer(ctx, &cfg)
+	if err != nil {
+		t.Fatalf("receiveCodeViaLocalServer error: %s", err)
+	}
+	if w := "AUTH_CODE"; resp.code != w {
+		t.Errorf("code wants %s but was %s", w, resp.code)
+	}
+}
+
+func TestLocalServerHandler_RedirectMiddleware(t *testing.T) {
+	var redirects int
+	h := &localServerHandler{
+		config: &Config{
+			State: "STATE",
+			LocalServerRedirectMiddleware: func(h http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					redirects++
+					if r.URL.Query().Get("code") == "REJECTED" {
+						http.Error(w, "too many requests", 429)
+						return
+					}
+					h.ServeHTTP(w, r)
+				})
+			},
+		},
+	}
+	for _, c := range []struct {
+		name       string
+		target     string
+		wantStatus int
+	}{
+		{"Index", "/", 302},
+		{"Rejected", "/?state=STATE&code=REJECTED", 429},
+		{"Accepted", "/?state=STATE&code=AUTH_CODE", 200},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", c.target, nil))
+			if w.Code != c.wantStatus {
+				t.Errorf("status wants %d but was %d", c.wantStatus, w.Code)
+			}
+		})
+	}
+	if redirects != 2 {
+		t.Errorf("number of redirects wants 2 but was %d", redirects)
+	}
+}
+
+func TestLocalServerHandler_ResponseBodyLimit(t *testing.T) {
+	var logs bytes.Buffer
+	h := &localServerHandler{
+		config: &Config{
+			State:                        "STATE",
+			LocalServerResponseBodyLimit: 16,
+			Logger:                       slog.New(slog.NewTextHandler(&logs, nil)),
+		},
+	}
+	t.Run("WithinLimit", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/?state=STATE&code=AUTH_CODE", strings.NewReader("small")))
+		if w.Code != 200 {
+			t.Errorf("status wants 200 but was %d", w.Code)
+		}
+	})
+	t.Run("ExceedsLimit", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/?state=STATE&code=AUTH_CODE", strings.NewReader(strings.Repeat("x", 17))))
+		if w.Code != 413 {
+			t.Errorf("status wants 413 but was %d", w.Code)
+		}
+		if !strings.Contains(logs.String(), "level=WARN") {
+			t.Errorf("logs wants a warning but was %q", logs.String())
+		}
+	})
+}
+
+func TestLocalServerHandler_ErrorHTML(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{"Default", Config{}, `<html><body>Authorization failed: access &lt;denied&gt;<script>window.close()</script></body></html>`},
+		{"SuppressWindowClose", Config{LocalServerSuppressWindowClose: true}, `<html><body>Authorization failed: access &lt;denied&gt;</body></html>`},
+		{"Custom", Config{LocalServerErrorHTML: `<p>{{.ErrorCode}}</p>`}, `<p>access_denied</p>`},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			h := &localServerHandler{config: &c.cfg}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", "/?error=access_denied&error_description=access+%3Cdenied%3E", nil))
+			if w.Code != 500 {
+				t.Errorf("status wants 500 but was %d", w.Code)
+			}
+			if got := w.Body.String(); got != c.want {
+				t.Errorf("body wants %s but was %s", c.want, got)
+			}
+		})
+	}
+}
+
+func TestLocalServerHandler_RandomizePath(t *testing.T) {
+	cfg := Config{State: "STATE", LocalServerRandomizePath: true}
+	if err := cfg.validateAndSetDefaults(); err != nil {
+		t.Fatalf("validateAndSetDefaults error: %s", err)
+	}
+	path := cfg.localServerPath()
+	if len(path) != 33 {
+		t.Fatalf("path wants a slash and 32 hex characters but was %s", path)
+	}
+	for _, c := range []struct {
+		name       string
+		target     string
+		wantStatus int
+	}{
+		{"Root", "/?state=STATE&code=AUTH_CODE", 404},
+		{"AnotherPath", "/0123456789abcdef0123456789abcdef?state=STATE&code=AUTH_CODE", 404},
+		{"Index", path, 302},
+		{"Redirect", path + "?state=STATE&code=AUTH_CODE", 200},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			h := &localServerHandler{config: &cfg, responseCh: make(chan *authorizationResponse, 1)}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", c.target, nil))
+			if w.Code != c.wantStatus {
+				t.Errorf("status wants %d but was %d", c.wantStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestLocalServerHandler_AcceptJSON(t *testing.T) {
+	for _, c := range []struct {
+		name       string
+		target     string
+		accept     string
+		wantStatus int
+		wantType   string
+		wantBody   string
+	}{
+		{"Code", "/?state=STATE&code=AUTH_CODE", "application/json", 200, "application/json", `{"status":"ok","code":"AUTH_CODE"}` + "\n"},
+		{"CodeWithQuality", "/?state=STATE&code=AUTH_CODE", "text/plain;q=0.5, application/json;q=0.9", 200, "application/json", `{"status":"ok","code":"AUTH_CODE"}` + "\n"},
+		{"Error", "/?error=access_denied&error_description=DENIED", "application/json", 500, "application/json", `{"status":"error","error":"access_denied","error_description":"DENIED"}` + "\n"},
+		{"HTML", "/?state=STATE&code=AUTH_CODE", "text/html", 200, "text/html", DefaultLocalServerSuccessHTML},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			h := &localServerHandler{
+				config: &Config{State: "STATE", LocalServerSuccessHTML: DefaultLocalServerSuccessHTML},
+			}
+			r := httptest.NewRequest("GET", c.target, nil)
+			r.Header.Set("Accept", c.accept)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != c.wantStatus {
+				t.Errorf("status wants %d but was %d", c.wantStatus, w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); got != c.wantType {
+				t.Errorf("Content-Type wants %s but was %s", c.wantType, got)
+			}
+			if got := w.Body.String(); got != c.wantBody {
+				t.Errorf("body wants %s but was %s", c.wantBody, got)
+			}
+		})
+	}
+}
+
+func TestLocalServerHandler_AlreadyUsedHTML(t *testing.T) {
+	for _, c := range []struct {
+		name            string
+		alreadyUsedHTML string
+		want            string
+	}{
+		{"Default", "", DefaultLocalServerAlreadyUsedHTML},
+		{"Custom", "<html><body>ALREADY_USED</body></html>", "<html><body>ALREADY_USED</body></html>"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			h := &localServerHandler{
+				config: &Config{State: "STATE", LocalServerAlreadyUsedHTML: c.alreadyUsedHTML},
+			}
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/?state=STATE&code=AUTH_CODE", nil))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", "/?state=STATE&code=ANOTHER_CODE", nil))
+			if w.Code != 410 {
+				t.Errorf("status wants 410 but was %d", w.Code)
+			}
+			if got := w.Body.String(); got != c.want {
+				t.Errorf("body wants %s but was %s", c.want, got)
+			}
+		})
+	}
+}
+
+func TestLocalServerHandler_VerboseHeaders(t *testing.T) {
+	var logs bytes.Buffer
+	h := &localServerHandler{
+		config: &Config{
+			State:                     "STATE",
+			LocalServerVerboseHeaders: true,
+			Logger:                    slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		},
+	}
+	r := httptest.NewRequest("GET", "/?state=STATE&code=AUTH_CODE", nil)
+	r.Header.Set("User-Agent", "USER_AGENT")
+	r.Header.Set("Cookie", "SECRET_COOKIE")
+	r.Header.Set("Authorization", "Bearer SECRET_TOKEN")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	got := logs.String()
+	if !strings.Contains(got, "headers.User-Agent=USER_AGENT") {
+		t.Errorf("log wants the User-Agent header but was %s", got)
+	}
+	if strings.Contains(got, "SECRET") {
+		t.Errorf("log wants the credentials redacted but was %s", got)
+	}
+	if !strings.Contains(got, "headers.Cookie=REDACTED") {
+		t.Errorf("log wants the Cookie header redacted but was %s", got)
+	}
+}
+
+func TestReceiveCodeViaLocalServer_RequestTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+	defer cancel()
+	readyCh := make(chan string, 1)
+	cfg := Config{
+		State:                     "STATE",
+		LocalServerRequestTimeout: 50 * time.Millisecond,
+		LocalServerMiddleware: func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("code") == "SLOW_CODE" {
+					select {
+					case <-r.Context().Done():
+					case <-time.After(time.Second):
+					}
+					return
+				}
+				h.ServeHTTP(w, r)
+			})
+		},
+		LocalServerReadyChan: readyCh,
+	}
+	if err := cfg.validateAndSetDefaults(); err != nil {
+		t.Fatalf("validateAndSetDefaults error: %s", err)
+	}
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		u := <-readyCh
+		resp, err := client.Get(u + "/?state=STATE&code=SLOW_CODE")
+		if err != nil {
+			t.Errorf("could not send a request: %s", err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode != 503 {
+			t.Errorf("status wants 503 but was %d", resp.StatusCode)
+		}
+		// the local server still accepts a request
+		resp, err = client.Get(u + "/?state=STATE&code=AUTH_CODE")
+		if err != nil {
+			t.Errorf("could not send a request: %s", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+	resp, err := receiveCodeViaLocalServer(ctx, &cfg)
+	<-done
+	if err != nil {
+		t.Fatalf("receiveCodeViaLocalServer error: %s", err)
+	}
+	if w := "AUTH_CODE"; resp.code != w {
+		t.Errorf("code wants %s but was %s", w, resp.code)
+	}
+}
+
+func TestRequestTimeoutHandler(t *testing.T) {
+	t.Run("Flush", func(t *testing.T) {
+		h := requestTimeoutHandler(&Config{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := r.Context().Deadline(); !ok {
+				t.Errorf("context wants a deadline")
+			}
+			f, ok := w.(http.Flusher)
+			if !ok {
+				t.Fatalf("ResponseWriter wants http.Flusher")
+			}
+			w.WriteHeader(200)
+			f.Flush()
+		}), time.Second)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if !w.Flushed {
+			t.Errorf("response wants to be flushed")
+		}
+	})
+	t.Run("Timeout", func(t *testing.T) {
+		h := requestTimeoutHandler(&Config{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}), 10*time.Millisecond)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != 503 {
+			t.Errorf("status wants 503 but was %d", w.Code)
+		}
+	})
+}
+
+func TestReceiveCodeViaLocalServer_OnStarted(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+	defer cancel()
+	readyCh := make(chan string, 1)
+	var port int
+	cfg := Config{
+		State: "STATE",
+		LocalServerOnStarted: func(addr net.Addr) {
+			if len(readyCh) > 0 {
+				t.Errorf("LocalServerOnStarted wants to be called before LocalServerReadyChan")
+			}
+			port = addr.(*net.TCPAddr).Port
+		},
+		LocalServerReadyChan: readyCh,
+	}
+	if err := cfg.validateAndSetDefaults(); err != nil {
+		t.Fatalf("validateAndSetDefaults error: %s", err)
+	}
+	go func() {
+		resp, err := http.Get(<-readyCh + "/?state=STATE&code=AUTH_CODE")
+		if err != nil {
+			t.Errorf("could not send a request: %s", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+	if _, err := receiveCodeViaLocalServer(ctx, &cfg); err != nil {
+		t.Fatalf("receiveCodeViaLocalServer error: %s", err)
+	}
+	if w := fmt.Sprintf("http://localhost:%d", port); cfg.OAuth2Config.RedirectURL != w {
+		t.Errorf("RedirectURL wants %s but was %s", w, cfg.OAuth2Config.RedirectURL)
+	}
+}
+
+func TestReceiveCodeViaLocalServer_OAuth2ConfigMutator(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+	defer cancel()
+	readyCh := make(chan string, 1)
+	var redirectURL string
+	cfg := Config{
+		State:        "STATE",
+		OAuth2Config: oauth2.Config{Endpoint: oauth2.Endpoint{AuthURL: "https://example.com/auth"}},
+		OAuth2ConfigMutator: func(cfg *oauth2.Config) {
+			redirectURL = cfg.RedirectURL
+			cfg.Endpoint.AuthURL = "https://example.com/mutated"
+		},
+		LocalServerReadyChan: readyCh,
+	}
+	if err := cfg.validateAndSetDefaults(); err != nil {
+		t.Fatalf("validateAndSetDefaults error: %s", err)
+	}
+	client := &http.Client{
+		Transport:     &http.Transport{DisableKeepAlives: true},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	go func() {
+		u := <-readyCh
+		resp, err := client.Get(u)
+		if err != nil {
+			t.Errorf("could not send a request: %s", err)
+			return
+		}
+		resp.Body.Close()
+		if location := resp.Header.Get("Location"); !strings.HasPrefix(location, "https://example.com/mutated?") {
+			t.Errorf("Location wants the mutated URL but was %s", location)
+		}
+		resp, err = client.Get(u + "/?state=STATE&code=AUTH_CODE")
+		if err != nil {
+			t.Errorf("could not send a request: %s", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+	if _, err := receiveCodeViaLocalServer(ctx, &cfg); err != nil {
+		t.Fatalf("receiveCodeViaLocalServer error: %s", err)
+	}
+	if redirectURL == "" || redirectURL != cfg.OAuth2Config.RedirectURL {
+		t.Errorf("OAuth2ConfigMutator wants the redirect URL %s but was %s", cfg.OAuth2Config.RedirectURL, redirectURL)
+	}
+}
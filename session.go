@@ -0,0 +1,101 @@
+package oauth2cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SessionDeduplicator records the states of GetToken calls,
+// in order to prevent the same authorization from running twice.
+type SessionDeduplicator interface {
+	// CheckAndSet records the state and returns true if it is new.
+	// It returns false if the state has been recorded recently.
+	CheckAndSet(state string) bool
+}
+
+// InMemorySessionDeduplicator is a SessionDeduplicator which records the states in memory.
+type InMemorySessionDeduplicator struct {
+	ttl    time.Duration
+	states sync.Map // state -> time.Time
+}
+
+// NewInMemorySessionDeduplicator returns an InMemorySessionDeduplicator.
+// A state is regarded as new again when the TTL has passed.
+func NewInMemorySessionDeduplicator(ttl time.Duration) *InMemorySessionDeduplicator {
+	return &InMemorySessionDeduplicator{ttl: ttl}
+}
+
+// CheckAndSet records the state and returns true if it is new.
+func (d *InMemorySessionDeduplicator) CheckAndSet(state string) bool {
+	now := time.Now()
+	d.states.Range(func(k, v interface{}) bool {
+		if now.Sub(v.(time.Time)) >= d.ttl {
+			d.states.Delete(k)
+		}
+		return true
+	})
+	_, loaded := d.states.LoadOrStore(state, now)
+	return !loaded
+}
+
+type inflightSession struct {
+	done   chan struct{}
+	result *GetTokenResult
+	err    error
+}
+
+// inflightSessionKey identifies a GetToken call by the provider, client and state,
+// so that the calls for different providers or clients do not share a result.
+type inflightSessionKey struct {
+	authURL  string
+	tokenURL string
+	clientID string
+	state    string
+}
+
+var (
+	inflightSessionsMu sync.Mutex
+	inflightSessions   = make(map[inflightSessionKey]*inflightSession)
+)
+
+// deduplicateSession calls the function unless another call with the same state is in progress.
+// If so, it waits for the other call and returns its result.
+func deduplicateSession(ctx context.Context, c *Config, f func(context.Context, *Config) (*GetTokenResult, error)) (*GetTokenResult, error) {
+	key := inflightSessionKey{
+		authURL:  c.OAuth2Config.Endpoint.AuthURL,
+		tokenURL: c.OAuth2Config.Endpoint.TokenURL,
+		clientID: c.OAuth2Config.ClientID,
+		state:    c.State,
+	}
+	inflightSessionsMu.Lock()
+	if !c.SessionDeduplicator.CheckAndSet(c.State) {
+		s, ok := inflightSessions[key]
+		inflightSessionsMu.Unlock()
+		if !ok {
+			return nil, errors.New("the state has been used by another GetToken call recently")
+		}
+		select {
+		case <-s.done:
+			return s.result, s.err
+		case <-ctx.Done():
+			return nil, fmt.Errorf("context done while waiting for another GetToken call: %w", ctx.Err())
+		}
+	}
+	s := &inflightSession{done: make(chan struct{})}
+	inflightSessions[key] = s
+	inflightSessionsMu.Unlock()
+
+	// release the waiters even if f panics
+	s.err = errors.New("another GetToken call has panicked")
+	defer func() {
+		inflightSessionsMu.Lock()
+		delete(inflightSessions, key)
+		inflightSessionsMu.Unlock()
+		close(s.done)
+	}()
+	s.result, s.err = f(ctx, c)
+	return s.result, s.err
+}
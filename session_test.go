@@ -0,0 +1,136 @@
+package oauth2cli
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestInMemorySessionDeduplicator(t *testing.T) {
+	d := NewInMemorySessionDeduplicator(50 * time.Millisecond)
+	if !d.CheckAndSet("STATE") {
+		t.Errorf("CheckAndSet wants true for the new state")
+	}
+	if d.CheckAndSet("STATE") {
+		t.Errorf("CheckAndSet wants false for the recorded state")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if !d.CheckAndSet("STATE") {
+		t.Errorf("CheckAndSet wants true after the TTL")
+	}
+}
+
+func Test_deduplicateSession(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+	defer cancel()
+	cfg := &Config{State: "STATE", SessionDeduplicator: NewInMemorySessionDeduplicator(time.Minute)}
+	started, release := make(chan struct{}), make(chan struct{})
+	var calls int
+	f := func(context.Context, *Config) (*GetTokenResult, error) {
+		calls++
+		close(started)
+		<-release
+		return &GetTokenResult{Token: &oauth2.Token{AccessToken: "ACCESS_TOKEN"}}, nil
+	}
+
+	var eg errgroup.Group
+	results := make([]*GetTokenResult, 2)
+	eg.Go(func() error {
+		r, err := deduplicateSession(ctx, cfg, f)
+		results[0] = r
+		return err
+	})
+	<-started
+	eg.Go(func() error {
+		r, err := deduplicateSession(ctx, cfg, f)
+		results[1] = r
+		return err
+	})
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	if err := eg.Wait(); err != nil {
+		t.Fatalf("deduplicateSession error: %s", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls wants 1 but was %d", calls)
+	}
+	for i, r := range results {
+		if r == nil || r.Token.AccessToken != "ACCESS_TOKEN" {
+			t.Errorf("results[%d] wants the token of the first call but was %+v", i, r)
+		}
+	}
+
+	// the state has been used by the finished call
+	if _, err := deduplicateSession(ctx, cfg, f); err == nil {
+		t.Errorf("deduplicateSession wants error but was nil")
+	}
+}
+
+func Test_deduplicateSession_Panic(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+	defer cancel()
+	cfg := &Config{State: "STATE_PANIC", SessionDeduplicator: NewInMemorySessionDeduplicator(time.Minute)}
+	started, release := make(chan struct{}), make(chan struct{})
+	f := func(context.Context, *Config) (*GetTokenResult, error) {
+		close(started)
+		<-release
+		panic("PANIC")
+	}
+
+	panicked := make(chan interface{})
+	go func() {
+		defer func() { panicked <- recover() }()
+		_, _ = deduplicateSession(ctx, cfg, f)
+	}()
+	<-started
+	waiterErr := make(chan error)
+	go func() {
+		_, err := deduplicateSession(ctx, cfg, f)
+		waiterErr <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	if r := <-panicked; r != "PANIC" {
+		t.Errorf("recover wants PANIC but was %v", r)
+	}
+	if err := <-waiterErr; err == nil || ctx.Err() != nil {
+		t.Errorf("waiter wants the error of the panicked call but was %v", err)
+	}
+}
+
+func Test_deduplicateSession_DifferentClients(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+	defer cancel()
+	d := NewInMemorySessionDeduplicator(time.Minute)
+	started, release := make(chan struct{}), make(chan struct{})
+	f1 := func(context.Context, *Config) (*GetTokenResult, error) {
+		close(started)
+		<-release
+		return &GetTokenResult{Token: &oauth2.Token{AccessToken: "ACCESS_TOKEN_1"}}, nil
+	}
+	f2 := func(context.Context, *Config) (*GetTokenResult, error) {
+		return &GetTokenResult{Token: &oauth2.Token{AccessToken: "ACCESS_TOKEN_2"}}, nil
+	}
+
+	var eg errgroup.Group
+	eg.Go(func() error {
+		cfg := &Config{State: "STATE_CLIENTS", SessionDeduplicator: d}
+		cfg.OAuth2Config.ClientID = "CLIENT_1"
+		_, err := deduplicateSession(ctx, cfg, f1)
+		return err
+	})
+	<-started
+	cfg := &Config{State: "STATE_CLIENTS", SessionDeduplicator: d}
+	cfg.OAuth2Config.ClientID = "CLIENT_2"
+	r, err := deduplicateSession(ctx, cfg, f2)
+	close(release)
+	if err := eg.Wait(); err != nil {
+		t.Fatalf("deduplicateSession error: %s", err)
+	}
+	if err == nil {
+		t.Errorf("deduplicateSession wants error for the recorded state but was %+v", r)
+	}
+}
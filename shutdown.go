@@ -0,0 +1,56 @@
+package oauth2cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+var (
+	shutdownOnce sync.Once
+	shutdownCh   = make(chan struct{})
+)
+
+// RegisterShutdownHook returns a context which is canceled when the process receives SIGINT or SIGTERM,
+// so that GetToken with the context shuts down the local server gracefully.
+// Note that SIGKILL cannot be caught.
+//
+// This does not block. The signal handler is registered only once in the process,
+// and it is unregistered on the first signal, i.e. the next signal terminates the process.
+// You should call the cancel function to release the resources of the context.
+func RegisterShutdownHook(ctx context.Context) (context.Context, context.CancelFunc) {
+	shutdownOnce.Do(func() {
+		sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-sigCtx.Done()
+			stop()
+			close(shutdownCh)
+		}()
+	})
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-shutdownCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// withOSSignals returns a function which calls f with a child context canceled on SIGINT or SIGTERM.
+// This is used if Config.HandleOSSignals is true.
+func withOSSignals(f func(context.Context, *Config) (*GetTokenResult, error)) func(context.Context, *Config) (*GetTokenResult, error) {
+	return func(ctx context.Context, c *Config) (*GetTokenResult, error) {
+		sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		result, err := f(sigCtx, c)
+		if err != nil && sigCtx.Err() != nil && ctx.Err() == nil {
+			return nil, fmt.Errorf("canceled by the signal: %w", err)
+		}
+		return result, err
+	}
+}
@@ -0,0 +1,66 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package oauth2cli
+
+import (
+	"context"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRegisterShutdownHook(t *testing.T) {
+	ctx1, cancel1 := RegisterShutdownHook(context.TODO())
+	defer cancel1()
+	ctx2, cancel2 := RegisterShutdownHook(context.TODO())
+	defer cancel2()
+	select {
+	case <-ctx1.Done():
+		t.Fatalf("context should not be canceled before the signal")
+	default:
+	}
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatalf("could not send the signal: %s", err)
+	}
+	for _, ctx := range []context.Context{ctx1, ctx2} {
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+			t.Errorf("context should be canceled on the signal")
+		}
+	}
+
+	// a hook registered after the signal is canceled immediately
+	ctx3, cancel3 := RegisterShutdownHook(context.TODO())
+	defer cancel3()
+	select {
+	case <-ctx3.Done():
+	case <-time.After(time.Second):
+		t.Errorf("context should be canceled after the signal")
+	}
+}
+
+func TestWithOSSignals(t *testing.T) {
+	ctx := context.TODO()
+	f := withOSSignals(func(ctx context.Context, _ *Config) (*GetTokenResult, error) {
+		if err := syscall.Kill(syscall.Getpid(), syscall.SIGINT); err != nil {
+			t.Fatalf("could not send the signal: %s", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+			return nil, nil
+		}
+	})
+	_, err := f(ctx, &Config{})
+	if err == nil || !strings.Contains(err.Error(), "canceled by the signal") {
+		t.Errorf("error wants the cancellation by the signal but was %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Errorf("context of the caller wants not canceled but was %s", ctx.Err())
+	}
+}
@@ -0,0 +1,139 @@
+package oauth2cli
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const defaultStatsInterval = 1 * time.Second
+
+// Phases of GetTokenStats.
+const (
+	GetTokenPhaseStarting                = "Starting"
+	GetTokenPhaseWaitingForAuthorization = "WaitingForAuthorization"
+	GetTokenPhaseExchanging              = "Exchanging"
+	GetTokenPhaseDone                    = "Done"
+)
+
+// GetTokenStats represents a snapshot of a GetToken call.
+type GetTokenStats struct {
+	StartedAt time.Time
+	// Address of the local server, e.g. 127.0.0.1:8000.
+	LocalServerAddr string
+	// True if the browser has accessed the local server.
+	BrowserOpened   bool
+	BrowserOpenedAt time.Time
+	// Non-nil if the authorization code has been received.
+	CodeReceivedAt *time.Time
+	// Number of the token requests.
+	ExchangeAttempts int
+	// Current phase, such as GetTokenPhaseWaitingForAuthorization.
+	CurrentPhase string
+}
+
+type statsRecorder struct {
+	mu    sync.Mutex
+	stats GetTokenStats
+}
+
+func newStatsRecorder() *statsRecorder {
+	return &statsRecorder{stats: GetTokenStats{
+		StartedAt:    time.Now(),
+		CurrentPhase: GetTokenPhaseStarting,
+	}}
+}
+
+// The following methods do nothing if the receiver is nil.
+
+func (r *statsRecorder) update(f func(s *GetTokenStats)) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f(&r.stats)
+}
+
+func (r *statsRecorder) localServerStarted(addr string) {
+	r.update(func(s *GetTokenStats) {
+		s.LocalServerAddr = addr
+		s.CurrentPhase = GetTokenPhaseWaitingForAuthorization
+	})
+}
+
+func (r *statsRecorder) browserOpened() {
+	r.update(func(s *GetTokenStats) {
+		if !s.BrowserOpened {
+			s.BrowserOpened = true
+			s.BrowserOpenedAt = time.Now()
+		}
+	})
+}
+
+func (r *statsRecorder) codeReceived() {
+	r.update(func(s *GetTokenStats) {
+		now := time.Now()
+		s.CodeReceivedAt = &now
+	})
+}
+
+func (r *statsRecorder) exchangeStarted() {
+	r.update(func(s *GetTokenStats) {
+		s.ExchangeAttempts++
+		s.CurrentPhase = GetTokenPhaseExchanging
+	})
+}
+
+func (r *statsRecorder) snapshot() GetTokenStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.stats
+	if s.CodeReceivedAt != nil {
+		t := *s.CodeReceivedAt
+		s.CodeReceivedAt = &t
+	}
+	return s
+}
+
+// run sends a snapshot to the channel on every interval until the context is done.
+// It does not block if the channel is not ready.
+func (r *statsRecorder) run(ctx context.Context, ch chan<- GetTokenStats, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			select {
+			case ch <- r.snapshot():
+			default:
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// startStats starts sending the stats if Config.StatsChan is set.
+// The returned function stops it and sends the last snapshot.
+func startStats(ctx context.Context, c *Config) func() {
+	if c.StatsChan == nil {
+		return func() {}
+	}
+	c.stats = newStatsRecorder()
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.stats.run(ctx, c.StatsChan, c.StatsInterval)
+	}()
+	return func() {
+		cancel()
+		<-done
+		c.stats.update(func(s *GetTokenStats) { s.CurrentPhase = GetTokenPhaseDone })
+		select {
+		case c.StatsChan <- c.stats.snapshot():
+		default:
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package oauth2cli_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/int128/oauth2cli"
+	oauth2clitesting "github.com/int128/oauth2cli/testing"
+)
+
+func TestGetToken_StatsChan(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+	defer cancel()
+	cfg, _ := oauth2clitesting.GoogleTestConfig(t)
+	statsCh := make(chan oauth2cli.GetTokenStats, 100)
+	cfg.StatsChan = statsCh
+	cfg.StatsInterval = 10 * time.Millisecond
+	if _, err := oauth2cli.GetToken(ctx, cfg); err != nil {
+		t.Fatalf("GetToken error: %s", err)
+	}
+	close(statsCh)
+	var last oauth2cli.GetTokenStats
+	for s := range statsCh {
+		last = s
+	}
+	if w := oauth2cli.GetTokenPhaseDone; last.CurrentPhase != w {
+		t.Errorf("CurrentPhase wants %s but was %s", w, last.CurrentPhase)
+	}
+	if last.LocalServerAddr == "" {
+		t.Errorf("LocalServerAddr wants non-empty but was empty")
+	}
+	if !last.BrowserOpened {
+		t.Errorf("BrowserOpened wants true but was false")
+	}
+	if last.CodeReceivedAt == nil {
+		t.Errorf("CodeReceivedAt wants non-nil but was nil")
+	}
+	if last.ExchangeAttempts != 1 {
+		t.Errorf("ExchangeAttempts wants 1 but was %d", last.ExchangeAttempts)
+	}
+}
@@ -0,0 +1,67 @@
+package testing
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+
+	"github.com/int128/oauth2cli"
+)
+
+// NewAutoNavigatingBrowser returns a BrowserOpener which behaves like a browser without any user interaction.
+// It sends a request to the URL of the local server and follows the redirects,
+// i.e. the authorization request to the mock server and the authorization response to the local server.
+// It also follows the meta refresh without waiting,
+// such as the page of oauth2cli.DefaultLocalServerTimeoutHTML.
+// It returns an error if the final response is not 200.
+//
+// If httpClient is nil, http.DefaultClient is used.
+func NewAutoNavigatingBrowser(httpClient *http.Client) oauth2cli.BrowserOpener {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return oauth2cli.BrowserOpenerFunc(func(u string) error {
+		for i := 0; i < maxMetaRefreshes; i++ {
+			next, err := followMetaRefresh(httpClient, u)
+			if err != nil {
+				return err
+			}
+			if next == "" {
+				return nil
+			}
+			u = next
+		}
+		return fmt.Errorf("stopped after %d meta refreshes", maxMetaRefreshes)
+	})
+}
+
+const maxMetaRefreshes = 10
+
+var metaRefreshPattern = regexp.MustCompile(`(?i)<meta\s+http-equiv="refresh"\s+content="\d+;\s*url=([^"]+)"`)
+
+// followMetaRefresh sends a request to the URL and returns the URL of the meta refresh if present.
+func followMetaRefresh(httpClient *http.Client, u string) (string, error) {
+	resp, err := httpClient.Get(u)
+	if err != nil {
+		return "", fmt.Errorf("could not send a request: %w", err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("could not read the response body: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("status wants 200 but was %d", resp.StatusCode)
+	}
+	m := metaRefreshPattern.FindSubmatch(b)
+	if m == nil {
+		return "", nil
+	}
+	next, err := resp.Request.URL.Parse(html.UnescapeString(string(m[1])))
+	if err != nil {
+		return "", fmt.Errorf("invalid meta refresh URL: %w", err)
+	}
+	return next.String(), nil
+}
@@ -0,0 +1,61 @@
+package testing
+
+import (
+	"context"
+	gotesting "testing"
+	"time"
+
+	"github.com/int128/oauth2cli"
+	"golang.org/x/oauth2"
+)
+
+func TestNewAutoNavigatingBrowser(t *gotesting.T) {
+	s := NewMockServer(t, MockServerConfig{
+		AuthorizationPath: "/authorize",
+		TokenPath:         "/token",
+		TokenResponseBody: `{"access_token":"ACCESS_TOKEN","token_type":"Bearer","expires_in":3600}`,
+	})
+	cfg := oauth2cli.Config{
+		OAuth2Config: oauth2.Config{
+			ClientID:     "YOUR_CLIENT_ID",
+			ClientSecret: "YOUR_CLIENT_SECRET",
+			Endpoint:     oauth2.Endpoint{AuthURL: s.AuthorizationURL(), TokenURL: s.TokenURL(), AuthStyle: oauth2.AuthStyleInParams},
+		},
+		BrowserOpener: NewAutoNavigatingBrowser(nil),
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+	defer cancel()
+	token, err := oauth2cli.GetToken(ctx, cfg)
+	if err != nil {
+		t.Fatalf("could not get a token: %s", err)
+	}
+	if w := "ACCESS_TOKEN"; token.AccessToken != w {
+		t.Errorf("AccessToken wants %s but was %s", w, token.AccessToken)
+	}
+}
+
+func TestNewAutoNavigatingBrowser_AuthorizationTimeout(t *gotesting.T) {
+	s := NewMockServer(t, MockServerConfig{
+		AuthorizationPath: "/authorize",
+		TokenPath:         "/token",
+		TokenResponseBody: `{"access_token":"ACCESS_TOKEN","token_type":"Bearer","expires_in":3600}`,
+	})
+	cfg := oauth2cli.Config{
+		OAuth2Config: oauth2.Config{
+			ClientID:     "YOUR_CLIENT_ID",
+			ClientSecret: "YOUR_CLIENT_SECRET",
+			Endpoint:     oauth2.Endpoint{AuthURL: s.AuthorizationURL(), TokenURL: s.TokenURL(), AuthStyle: oauth2.AuthStyleInParams},
+		},
+		AuthorizationTimeout: 5 * time.Minute,
+		BrowserOpener:        NewAutoNavigatingBrowser(nil),
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+	defer cancel()
+	token, err := oauth2cli.GetToken(ctx, cfg)
+	if err != nil {
+		t.Fatalf("could not get a token: %s", err)
+	}
+	if w := "ACCESS_TOKEN"; token.AccessToken != w {
+		t.Errorf("AccessToken wants %s but was %s", w, token.AccessToken)
+	}
+}
@@ -0,0 +1,227 @@
+// Package testing provides a mock authorization server and test fixtures for oauth2cli.
+// This supports the authorization code grant described as:
+// https://tools.ietf.org/html/rfc6749#section-4.1
+package testing
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	gotesting "testing"
+	"time"
+)
+
+// MockServerConfig represents quirks of a provider.
+type MockServerConfig struct {
+	// Path of the authorization endpoint, e.g. /authorize.
+	AuthorizationPath string
+	// Path of the token endpoint, e.g. /token.
+	TokenPath string
+	// Scopes which must be requested in the authorization request.
+	RequiredScopes []string
+	// Code returned in the authorization response.
+	// Default to "AUTH_CODE".
+	Code string
+	// Additional parameters of the authorization response, e.g. session_state.
+	AuthorizationResponseParams url.Values
+	// Content type of the token response.
+	// Default to "application/json".
+	TokenResponseContentType string
+	// If true, the authorization request must have a S256 code challenge,
+	// and the token request must have the code verifier of it.
+	// See https://tools.ietf.org/html/rfc7636
+	RequirePKCE bool
+	// Body of the token response.
+	// You can use "{{ID_TOKEN}}" as a placeholder for an unsigned ID token.
+	TokenResponseBody string
+}
+
+// MockServer is a stub of the authorization server.
+// It records the requests received from the client.
+type MockServer struct {
+	*httptest.Server
+	t      gotesting.TB
+	config MockServerConfig
+
+	mu                    sync.Mutex
+	authorizationRequests []url.Values
+	tokenRequests         []url.Values
+	codeChallenge         string
+}
+
+// NewMockServer starts a mock server.
+// The server will be closed when the test finishes.
+func NewMockServer(t gotesting.TB, config MockServerConfig) *MockServer {
+	if config.Code == "" {
+		config.Code = "AUTH_CODE"
+	}
+	if config.TokenResponseContentType == "" {
+		config.TokenResponseContentType = "application/json"
+	}
+	s := &MockServer{t: t, config: config}
+	s.Server = httptest.NewServer(s)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// AuthorizationURL returns the URL of the authorization endpoint.
+func (s *MockServer) AuthorizationURL() string {
+	return s.URL + s.config.AuthorizationPath
+}
+
+// TokenURL returns the URL of the token endpoint.
+func (s *MockServer) TokenURL() string {
+	return s.URL + s.config.TokenPath
+}
+
+// AuthorizationRequests returns the query parameters of the authorization requests received so far.
+func (s *MockServer) AuthorizationRequests() []url.Values {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]url.Values(nil), s.authorizationRequests...)
+}
+
+// TokenRequests returns the form parameters of the token requests received so far.
+func (s *MockServer) TokenRequests() []url.Values {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]url.Values(nil), s.tokenRequests...)
+}
+
+func (s *MockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.t.Logf("mockServer: %s %s", r.Method, r.RequestURI)
+	switch {
+	case r.Method == "GET" && r.URL.Path == s.config.AuthorizationPath:
+		s.serveAuthorization(w, r)
+	case r.Method == "POST" && r.URL.Path == s.config.TokenPath:
+		s.serveToken(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *MockServer) serveAuthorization(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	s.mu.Lock()
+	s.authorizationRequests = append(s.authorizationRequests, q)
+	s.mu.Unlock()
+
+	redirectURI, state := q.Get("redirect_uri"), q.Get("state")
+	if redirectURI == "" {
+		s.t.Errorf("redirect_uri is missing")
+		http.Error(w, "redirect_uri is missing", 400)
+		return
+	}
+	if w := "code"; q.Get("response_type") != w {
+		s.t.Errorf("response_type wants %s but was %s", w, q.Get("response_type"))
+	}
+	if state == "" {
+		s.t.Errorf("state is missing")
+	}
+	scopes := strings.Fields(q.Get("scope"))
+	for _, required := range s.config.RequiredScopes {
+		if !contains(scopes, required) {
+			s.t.Errorf("scope %s is required but was %s", required, q.Get("scope"))
+			http.Redirect(w, r, fmt.Sprintf("%s?error=invalid_scope&state=%s", redirectURI, url.QueryEscape(state)), 302)
+			return
+		}
+	}
+	if s.config.RequirePKCE {
+		if method := q.Get("code_challenge_method"); method != "S256" {
+			s.t.Errorf("code_challenge_method wants S256 but was %s", method)
+			http.Redirect(w, r, fmt.Sprintf("%s?error=invalid_request&state=%s", redirectURI, url.QueryEscape(state)), 302)
+			return
+		}
+		if q.Get("code_challenge") == "" {
+			s.t.Errorf("code_challenge is missing")
+			http.Redirect(w, r, fmt.Sprintf("%s?error=invalid_request&state=%s", redirectURI, url.QueryEscape(state)), 302)
+			return
+		}
+		s.mu.Lock()
+		s.codeChallenge = q.Get("code_challenge")
+		s.mu.Unlock()
+	}
+	resp := url.Values{}
+	for k, v := range s.config.AuthorizationResponseParams {
+		resp[k] = v
+	}
+	resp.Set("code", s.config.Code)
+	resp.Set("state", state)
+	http.Redirect(w, r, redirectURI+"?"+resp.Encode(), 302)
+}
+
+func (s *MockServer) serveToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		s.t.Errorf("could not parse form: %s", err)
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	s.mu.Lock()
+	s.tokenRequests = append(s.tokenRequests, r.Form)
+	s.mu.Unlock()
+
+	if w := "authorization_code"; r.Form.Get("grant_type") != w {
+		s.t.Errorf("grant_type wants %s but was %s", w, r.Form.Get("grant_type"))
+	}
+	if code := r.Form.Get("code"); code != s.config.Code {
+		s.t.Errorf("code wants %s but was %s", s.config.Code, code)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(400)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+	if r.Form.Get("redirect_uri") == "" {
+		s.t.Errorf("redirect_uri is missing")
+	}
+	if s.config.RequirePKCE {
+		s.mu.Lock()
+		codeChallenge := s.codeChallenge
+		s.mu.Unlock()
+		if got := computeS256(r.Form.Get("code_verifier")); got != codeChallenge {
+			s.t.Errorf("S256 of code_verifier wants %s but was %s", codeChallenge, got)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(400)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+	}
+	body := strings.Replace(s.config.TokenResponseBody, "{{ID_TOKEN}}", newUnsignedIDToken(s.URL), -1)
+	w.Header().Set("Content-Type", s.config.TokenResponseContentType)
+	if _, err := w.Write([]byte(body)); err != nil {
+		s.t.Errorf("could not write the response body: %s", err)
+	}
+}
+
+func newUnsignedIDToken(issuer string) string {
+	header, _ := json.Marshal(map[string]interface{}{"alg": "none", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   issuer,
+		"sub":   "SUBJECT",
+		"aud":   "YOUR_CLIENT_ID",
+		"email": "user@example.com",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+	})
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(header) + "." + enc.EncodeToString(claims) + "."
+}
+
+func computeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func contains(a []string, s string) bool {
+	for _, e := range a {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
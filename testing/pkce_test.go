@@ -0,0 +1,58 @@
+package testing
+
+import (
+	"context"
+	gotesting "testing"
+	"time"
+
+	"github.com/int128/oauth2cli"
+	"github.com/int128/oauth2cli/oauth2params"
+	"golang.org/x/oauth2"
+)
+
+func TestGetTokenWithPKCE(t *gotesting.T) {
+	s := NewMockServer(t, MockServerConfig{
+		AuthorizationPath: "/authorize",
+		TokenPath:         "/token",
+		RequirePKCE:       true,
+		TokenResponseBody: `{"access_token":"ACCESS_TOKEN","token_type":"Bearer","expires_in":3600,"refresh_token":"REFRESH_TOKEN"}`,
+	})
+	cfg := newTestConfig(t, s, oauth2.AuthStyleInParams, "openid")
+	pkce, err := oauth2params.NewPKCE()
+	if err != nil {
+		t.Fatalf("could not generate PKCE parameters: %s", err)
+	}
+	cfg.AuthCodeOptions = pkce.AuthCodeOptions()
+	cfg.TokenRequestOptions = pkce.TokenRequestOptions()
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+	defer cancel()
+	token, err := oauth2cli.GetToken(ctx, cfg)
+	if err != nil {
+		t.Fatalf("could not get a token: %s", err)
+	}
+	if w := "ACCESS_TOKEN"; token.AccessToken != w {
+		t.Errorf("AccessToken wants %s but was %s", w, token.AccessToken)
+	}
+	if w := "REFRESH_TOKEN"; token.RefreshToken != w {
+		t.Errorf("RefreshToken wants %s but was %s", w, token.RefreshToken)
+	}
+
+	authorizationRequests := s.AuthorizationRequests()
+	if len(authorizationRequests) != 1 {
+		t.Fatalf("number of authorization requests wants 1 but was %d", len(authorizationRequests))
+	}
+	if w := pkce.CodeChallenge; authorizationRequests[0].Get("code_challenge") != w {
+		t.Errorf("code_challenge wants %s but was %s", w, authorizationRequests[0].Get("code_challenge"))
+	}
+	tokenRequests := s.TokenRequests()
+	if len(tokenRequests) != 1 {
+		t.Fatalf("number of token requests wants 1 but was %d", len(tokenRequests))
+	}
+	if w := pkce.CodeVerifier; tokenRequests[0].Get("code_verifier") != w {
+		t.Errorf("code_verifier wants %s but was %s", w, tokenRequests[0].Get("code_verifier"))
+	}
+	if got := computeS256(tokenRequests[0].Get("code_verifier")); got != authorizationRequests[0].Get("code_challenge") {
+		t.Errorf("S256 of code_verifier wants %s but was %s", authorizationRequests[0].Get("code_challenge"), got)
+	}
+}
@@ -0,0 +1,95 @@
+package testing
+
+import (
+	"fmt"
+	"net/url"
+	gotesting "testing"
+
+	"github.com/int128/oauth2cli"
+	"golang.org/x/oauth2"
+)
+
+// OktaTestConfig returns a Config and a mock server which behaves like Okta.
+// GetToken with the Config completes the flow without any user interaction.
+func OktaTestConfig(t gotesting.TB) (oauth2cli.Config, *MockServer) {
+	s := NewMockServer(t, MockServerConfig{
+		AuthorizationPath: "/oauth2/default/v1/authorize",
+		TokenPath:         "/oauth2/default/v1/token",
+		RequiredScopes:    []string{"openid"},
+		TokenResponseBody: `{"token_type":"Bearer","expires_in":3600,"access_token":"ACCESS_TOKEN","scope":"openid profile email offline_access","refresh_token":"REFRESH_TOKEN","id_token":"{{ID_TOKEN}}"}`,
+	})
+	return newTestConfig(t, s, oauth2.AuthStyleInHeader, "openid", "profile", "email", "offline_access"), s
+}
+
+// AzureADTestConfig returns a Config and a mock server which behaves like Azure AD (Microsoft identity platform).
+// GetToken with the Config completes the flow without any user interaction.
+func AzureADTestConfig(t gotesting.TB, tenantID string) (oauth2cli.Config, *MockServer) {
+	s := NewMockServer(t, MockServerConfig{
+		AuthorizationPath:           fmt.Sprintf("/%s/oauth2/v2.0/authorize", tenantID),
+		TokenPath:                   fmt.Sprintf("/%s/oauth2/v2.0/token", tenantID),
+		RequiredScopes:              []string{"openid"},
+		AuthorizationResponseParams: url.Values{"session_state": {"SESSION_STATE"}},
+		TokenResponseBody:           `{"token_type":"Bearer","scope":"openid profile offline_access","expires_in":3599,"ext_expires_in":3599,"access_token":"ACCESS_TOKEN","refresh_token":"REFRESH_TOKEN","id_token":"{{ID_TOKEN}}"}`,
+	})
+	return newTestConfig(t, s, oauth2.AuthStyleInParams, "openid", "profile", "offline_access"), s
+}
+
+// GoogleTestConfig returns a Config and a mock server which behaves like Google.
+// GetToken with the Config completes the flow without any user interaction.
+func GoogleTestConfig(t gotesting.TB) (oauth2cli.Config, *MockServer) {
+	s := NewMockServer(t, MockServerConfig{
+		AuthorizationPath: "/o/oauth2/v2/auth",
+		TokenPath:         "/token",
+		RequiredScopes:    []string{"openid"},
+		TokenResponseBody: `{"access_token":"ACCESS_TOKEN","expires_in":3599,"refresh_token":"REFRESH_TOKEN","scope":"openid https://www.googleapis.com/auth/userinfo.email","token_type":"Bearer","id_token":"{{ID_TOKEN}}"}`,
+	})
+	return newTestConfig(t, s, oauth2.AuthStyleInParams, "openid", "email"), s
+}
+
+// GitHubTestConfig returns a Config and a mock server which behaves like GitHub.
+// The token response is form-encoded and has no expiry.
+// GetToken with the Config completes the flow without any user interaction.
+func GitHubTestConfig(t gotesting.TB) (oauth2cli.Config, *MockServer) {
+	s := NewMockServer(t, MockServerConfig{
+		AuthorizationPath:        "/login/oauth/authorize",
+		TokenPath:                "/login/oauth/access_token",
+		TokenResponseContentType: "application/x-www-form-urlencoded",
+		TokenResponseBody:        "access_token=ACCESS_TOKEN&scope=read%3Auser&token_type=bearer",
+	})
+	return newTestConfig(t, s, oauth2.AuthStyleInParams, "read:user"), s
+}
+
+func newTestConfig(t gotesting.TB, s *MockServer, authStyle oauth2.AuthStyle, scopes ...string) oauth2cli.Config {
+	readyCh := make(chan string)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for u := range readyCh {
+			if err := navigate(u); err != nil {
+				t.Errorf("could not navigate the browser: %s", err)
+			}
+		}
+	}()
+	t.Cleanup(func() {
+		close(readyCh)
+		<-done
+	})
+	return oauth2cli.Config{
+		OAuth2Config: oauth2.Config{
+			ClientID:     "YOUR_CLIENT_ID",
+			ClientSecret: "YOUR_CLIENT_SECRET",
+			Endpoint: oauth2.Endpoint{
+				AuthURL:   s.AuthorizationURL(),
+				TokenURL:  s.TokenURL(),
+				AuthStyle: authStyle,
+			},
+			Scopes: scopes,
+		},
+		LocalServerReadyChan: readyCh,
+	}
+}
+
+// navigate behaves like a browser which follows the redirects from the local server.
+func navigate(u string) error {
+	return NewAutoNavigatingBrowser(nil).OpenURL(u)
+}
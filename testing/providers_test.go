@@ -0,0 +1,50 @@
+package testing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/int128/oauth2cli"
+	oauth2clitesting "github.com/int128/oauth2cli/testing"
+)
+
+func TestProviderTestConfigs(t *testing.T) {
+	t.Run("Okta", func(t *testing.T) {
+		cfg, s := oauth2clitesting.OktaTestConfig(t)
+		getToken(t, cfg, s, true)
+	})
+	t.Run("AzureAD", func(t *testing.T) {
+		cfg, s := oauth2clitesting.AzureADTestConfig(t, "TENANT_ID")
+		getToken(t, cfg, s, true)
+	})
+	t.Run("Google", func(t *testing.T) {
+		cfg, s := oauth2clitesting.GoogleTestConfig(t)
+		getToken(t, cfg, s, true)
+	})
+	t.Run("GitHub", func(t *testing.T) {
+		cfg, s := oauth2clitesting.GitHubTestConfig(t)
+		getToken(t, cfg, s, false)
+	})
+}
+
+func getToken(t *testing.T, cfg oauth2cli.Config, s *oauth2clitesting.MockServer, wantIDToken bool) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+	defer cancel()
+	token, err := oauth2cli.GetToken(ctx, cfg)
+	if err != nil {
+		t.Fatalf("could not get a token: %s", err)
+	}
+	if w := "ACCESS_TOKEN"; token.AccessToken != w {
+		t.Errorf("AccessToken wants %s but was %s", w, token.AccessToken)
+	}
+	if idToken, _ := token.Extra("id_token").(string); (idToken != "") != wantIDToken {
+		t.Errorf("id_token wants present=%v but was %q", wantIDToken, idToken)
+	}
+	if n := len(s.AuthorizationRequests()); n != 1 {
+		t.Errorf("number of authorization requests wants 1 but was %d", n)
+	}
+	if n := len(s.TokenRequests()); n != 1 {
+		t.Errorf("number of token requests wants 1 but was %d", n)
+	}
+}
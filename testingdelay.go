@@ -0,0 +1,27 @@
+//go:build oauth2cli_testing
+// +build oauth2cli_testing
+
+package oauth2cli
+
+import (
+	"net/http"
+	"time"
+)
+
+// testingConfig has the options for testing.
+// This is available only in the build with the oauth2cli_testing tag.
+type testingConfig struct {
+	// Delay before the local server writes the response to the authorization response.
+	// This is useful to test the timeout handling. Default to 0.
+	LocalServerResponseDelayForTesting time.Duration
+}
+
+func (c *testingConfig) delayResponseForTesting(r *http.Request) {
+	if c.LocalServerResponseDelayForTesting == 0 {
+		return
+	}
+	select {
+	case <-time.After(c.LocalServerResponseDelayForTesting):
+	case <-r.Context().Done():
+	}
+}
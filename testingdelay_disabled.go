@@ -0,0 +1,12 @@
+//go:build !oauth2cli_testing
+// +build !oauth2cli_testing
+
+package oauth2cli
+
+import "net/http"
+
+// testingConfig has the options for testing.
+// This is empty unless the build has the oauth2cli_testing tag.
+type testingConfig struct{}
+
+func (*testingConfig) delayResponseForTesting(*http.Request) {}
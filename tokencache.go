@@ -0,0 +1,270 @@
+package oauth2cli
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// TokenCache stores tokens.
+type TokenCache interface {
+	// Load returns the token of the key.
+	// It returns nil if not found.
+	Load(key string) (*oauth2.Token, error)
+	// Save stores the token of the key.
+	Save(key string, token *oauth2.Token) error
+	// Remove deletes the token of the key.
+	// It returns nil if not found.
+	Remove(key string) error
+}
+
+// commandError represents an error of the command.
+type commandError struct {
+	name     string
+	exitCode int // -1 if the command did not exit
+	stderr   string
+	err      error
+}
+
+func (e *commandError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", e.name, e.err, e.stderr)
+}
+
+func (e *commandError) Unwrap() error { return e.err }
+
+// runCommand runs the command with the stdin and returns the stdout.
+// The secrets must be passed via the stdin, because the arguments are visible to other users.
+var runCommand = func(stdin string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		exitCode := -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+		return stdout.String(), &commandError{name: name, exitCode: exitCode, stderr: strings.TrimSpace(stderr.String()), err: err}
+	}
+	return stdout.String(), nil
+}
+
+// hasDBusService returns true if the service is running or activatable on the session bus.
+func hasDBusService(name string) bool {
+	for _, method := range []string{"ListNames", "ListActivatableNames"} {
+		out, err := runCommand("", "dbus-send", "--session", "--print-reply", "--dest=org.freedesktop.DBus",
+			"/org/freedesktop/DBus", "org.freedesktop.DBus."+method)
+		if err != nil {
+			return false
+		}
+		if strings.Contains(out, `"`+name+`"`) {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeToken encodes the token in base64 of JSON.
+func encodeToken(token *oauth2.Token) (string, error) {
+	b, err := json.Marshal(token)
+	if err != nil {
+		return "", fmt.Errorf("could not encode the token: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+func decodeToken(s string) (*oauth2.Token, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid cache: %w", err)
+	}
+	var token oauth2.Token
+	if err := json.Unmarshal(b, &token); err != nil {
+		return nil, fmt.Errorf("invalid cache: %w", err)
+	}
+	return &token, nil
+}
+
+// NewGNOMEKeyringTokenCache returns a TokenCache which stores the tokens in GNOME Keyring,
+// or any other implementation of the Secret Service (org.freedesktop.secrets).
+// This requires the secret-tool command.
+// It returns an error if the Secret Service is not available, e.g. a headless server,
+// so that you can fall back to another cache.
+func NewGNOMEKeyringTokenCache(service string) (TokenCache, error) {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return nil, fmt.Errorf("secret-tool is not available: %w", err)
+	}
+	if !hasDBusService("org.freedesktop.secrets") {
+		return nil, errors.New("secret service (org.freedesktop.secrets) is not available on the session bus")
+	}
+	return &gnomeKeyringTokenCache{service: service}, nil
+}
+
+type gnomeKeyringTokenCache struct {
+	service string
+}
+
+func (c *gnomeKeyringTokenCache) Load(key string) (*oauth2.Token, error) {
+	out, err := runCommand("", "secret-tool", "lookup", "service", c.service, "key", key)
+	if err != nil {
+		// secret-tool exits with 1 and no output if not found
+		var cmdErr *commandError
+		if errors.As(err, &cmdErr) && cmdErr.exitCode == 1 && cmdErr.stderr == "" && strings.TrimSpace(out) == "" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not read the keyring: %w", err)
+	}
+	return decodeToken(out)
+}
+
+func (c *gnomeKeyringTokenCache) Save(key string, token *oauth2.Token) error {
+	s, err := encodeToken(token)
+	if err != nil {
+		return err
+	}
+	label := fmt.Sprintf("%s token (%s)", c.service, key)
+	if _, err := runCommand(s, "secret-tool", "store", "--label="+label, "service", c.service, "key", key); err != nil {
+		return fmt.Errorf("could not write the keyring: %w", err)
+	}
+	return nil
+}
+
+func (c *gnomeKeyringTokenCache) Remove(key string) error {
+	// secret-tool succeeds even if not found
+	if _, err := runCommand("", "secret-tool", "clear", "service", c.service, "key", key); err != nil {
+		return fmt.Errorf("could not remove the keyring entry: %w", err)
+	}
+	return nil
+}
+
+// kwalletDaemon represents the D-Bus service of kwalletd.
+type kwalletDaemon struct {
+	service string
+	path    string
+}
+
+var kwalletDaemons = []kwalletDaemon{
+	{service: "org.kde.kwalletd6", path: "/modules/kwalletd6"},
+	{service: "org.kde.kwalletd5", path: "/modules/kwalletd5"},
+}
+
+// NewKDEWalletTokenCache returns a TokenCache which stores the tokens in the folder of KDE Wallet.
+// This requires the kwallet-query and dbus-send commands.
+// The token is passed to kwallet-query via the stdin, and never to the arguments.
+// It returns an error if kwalletd is not available on the session bus, e.g. a headless server,
+// so that you can fall back to another cache.
+func NewKDEWalletTokenCache(app, folder string) (TokenCache, error) {
+	for _, name := range []string{"kwallet-query", "dbus-send"} {
+		if _, err := exec.LookPath(name); err != nil {
+			return nil, fmt.Errorf("%s is not available: %w", name, err)
+		}
+	}
+	for _, daemon := range kwalletDaemons {
+		if hasDBusService(daemon.service) {
+			return &kdeWalletTokenCache{daemon: daemon, app: app, folder: folder}, nil
+		}
+	}
+	return nil, errors.New("KDE Wallet (kwalletd) is not available on the session bus")
+}
+
+type kdeWalletTokenCache struct {
+	daemon kwalletDaemon
+	app    string
+	folder string
+}
+
+// call calls the method of kwalletd and returns the first value of the reply.
+// The arguments must not contain any secret.
+func (c *kdeWalletTokenCache) call(method string, args ...string) (string, error) {
+	cmdArgs := append([]string{"--session", "--print-reply", "--dest=" + c.daemon.service, c.daemon.path, "org.kde.KWallet." + method}, args...)
+	out, err := runCommand("", "dbus-send", cmdArgs...)
+	if err != nil {
+		return "", fmt.Errorf("could not call %s: %w", method, err)
+	}
+	return parseDBusReply(out)
+}
+
+var dbusReplyPattern = regexp.MustCompile(`(?m)^\s+(?:int32|int64|boolean|string) (.*)$`)
+
+// parseDBusReply returns the first value in the output of dbus-send --print-reply.
+func parseDBusReply(out string) (string, error) {
+	m := dbusReplyPattern.FindStringSubmatch(out)
+	if m == nil {
+		return "", fmt.Errorf("unexpected reply: %s", out)
+	}
+	return strings.Trim(m[1], `"`), nil
+}
+
+// open opens the network wallet and returns the name and handle.
+func (c *kdeWalletTokenCache) open() (string, string, error) {
+	wallet, err := c.call("networkWallet")
+	if err != nil {
+		return "", "", err
+	}
+	handle, err := c.call("open", "string:"+wallet, "int64:0", "string:"+c.app)
+	if err != nil {
+		return "", "", err
+	}
+	if n, err := strconv.Atoi(handle); err != nil || n < 0 {
+		return "", "", fmt.Errorf("could not open the wallet %s", wallet)
+	}
+	return wallet, handle, nil
+}
+
+func (c *kdeWalletTokenCache) Load(key string) (*oauth2.Token, error) {
+	wallet, handle, err := c.open()
+	if err != nil {
+		return nil, err
+	}
+	has, err := c.call("hasEntry", "int32:"+handle, "string:"+c.folder, "string:"+key, "string:"+c.app)
+	if err != nil {
+		return nil, err
+	}
+	if has != "true" {
+		return nil, nil
+	}
+	out, err := runCommand("", "kwallet-query", "-f", c.folder, "-r", key, wallet)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the wallet: %w", err)
+	}
+	return decodeToken(out)
+}
+
+func (c *kdeWalletTokenCache) Save(key string, token *oauth2.Token) error {
+	s, err := encodeToken(token)
+	if err != nil {
+		return err
+	}
+	wallet, handle, err := c.open()
+	if err != nil {
+		return err
+	}
+	if _, err := c.call("createFolder", "int32:"+handle, "string:"+c.folder, "string:"+c.app); err != nil {
+		return err
+	}
+	if _, err := runCommand(s, "kwallet-query", "-f", c.folder, "-w", key, wallet); err != nil {
+		return fmt.Errorf("could not write the wallet: %w", err)
+	}
+	return nil
+}
+
+func (c *kdeWalletTokenCache) Remove(key string) error {
+	_, handle, err := c.open()
+	if err != nil {
+		return err
+	}
+	// the result code is not checked, because it is non-zero if not found
+	if _, err := c.call("removeEntry", "int32:"+handle, "string:"+c.folder, "string:"+key, "string:"+c.app); err != nil {
+		return err
+	}
+	return nil
+}
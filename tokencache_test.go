@@ -0,0 +1,187 @@
+package oauth2cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/oauth2"
+)
+
+func TestGNOMEKeyringTokenCache(t *testing.T) {
+	defer func(f func(string, string, ...string) (string, error)) { runCommand = f }(runCommand)
+	secrets := make(map[string]string)
+	runCommand = func(stdin string, name string, args ...string) (string, error) {
+		if name != "secret-tool" {
+			t.Fatalf("unexpected command %s", name)
+		}
+		switch args[0] {
+		case "store":
+			secrets[strings.Join(args[2:], " ")] = stdin
+			return "", nil
+		case "lookup":
+			s, ok := secrets[strings.Join(args[1:], " ")]
+			if !ok {
+				return "", &commandError{name: "secret-tool", exitCode: 1, err: errors.New("exit status 1")}
+			}
+			return s, nil
+		case "clear":
+			delete(secrets, strings.Join(args[1:], " "))
+			return "", nil
+		}
+		t.Fatalf("unexpected args %v", args)
+		return "", nil
+	}
+	cache := &gnomeKeyringTokenCache{service: "example"}
+	testTokenCache(t, cache)
+}
+
+func TestGNOMEKeyringTokenCache_LoadError(t *testing.T) {
+	defer func(f func(string, string, ...string) (string, error)) { runCommand = f }(runCommand)
+	runCommand = func(stdin string, name string, args ...string) (string, error) {
+		return "", &commandError{
+			name:     "secret-tool",
+			exitCode: 1,
+			stderr:   "secret-tool: Cannot autolaunch D-Bus without X11 $DISPLAY",
+			err:      errors.New("exit status 1"),
+		}
+	}
+	cache := &gnomeKeyringTokenCache{service: "example"}
+	token, err := cache.Load("KEY")
+	if err == nil {
+		t.Fatalf("Load wants error but was %+v", token)
+	}
+	t.Logf("expected error: %s", err)
+}
+
+func TestKDEWalletTokenCache(t *testing.T) {
+	defer func(f func(string, string, ...string) (string, error)) { runCommand = f }(runCommand)
+	secrets := make(map[string]string)
+	runCommand = func(stdin string, name string, args ...string) (string, error) {
+		switch name {
+		case "dbus-send":
+			if w := "--dest=org.kde.kwalletd5"; args[2] != w {
+				t.Errorf("dest wants %s but was %s", w, args[2])
+			}
+			switch method, params := args[4], args[5:]; method {
+			case "org.kde.KWallet.networkWallet":
+				return "method return\n   string \"kdewallet\"\n", nil
+			case "org.kde.KWallet.open":
+				return "method return\n   int32 1\n", nil
+			case "org.kde.KWallet.hasEntry":
+				_, ok := secrets[params[1]+" "+params[2]]
+				return fmt.Sprintf("method return\n   boolean %v\n", ok), nil
+			case "org.kde.KWallet.createFolder":
+				return "method return\n   boolean true\n", nil
+			case "org.kde.KWallet.removeEntry":
+				delete(secrets, params[1]+" "+params[2])
+				return "method return\n   int32 0\n", nil
+			}
+		case "kwallet-query":
+			// kwallet-query -f FOLDER -r|-w KEY WALLET
+			if w := "kdewallet"; args[4] != w {
+				t.Errorf("wallet wants %s but was %s", w, args[4])
+			}
+			entry := "string:" + args[1] + " string:" + args[3]
+			switch args[2] {
+			case "-w":
+				secrets[entry] = stdin
+				return "", nil
+			case "-r":
+				return secrets[entry] + "\n", nil
+			}
+		}
+		t.Fatalf("unexpected command %s %v", name, args)
+		return "", nil
+	}
+	cache := &kdeWalletTokenCache{daemon: kwalletDaemons[1], app: "example", folder: "tokens"}
+	testTokenCache(t, cache)
+}
+
+func TestKDEWalletTokenCache_SecretNotInArgs(t *testing.T) {
+	defer func(f func(string, string, ...string) (string, error)) { runCommand = f }(runCommand)
+	var stdins []string
+	runCommand = func(stdin string, name string, args ...string) (string, error) {
+		for _, arg := range args {
+			if strings.Contains(arg, "eyJ") {
+				t.Errorf("the token must not be passed to the arguments: %s %v", name, args)
+			}
+		}
+		if stdin != "" {
+			stdins = append(stdins, name)
+		}
+		switch name {
+		case "dbus-send":
+			if args[4] == "org.kde.KWallet.networkWallet" {
+				return "method return\n   string \"kdewallet\"\n", nil
+			}
+			return "method return\n   int32 1\n", nil
+		}
+		return "", nil
+	}
+	cache := &kdeWalletTokenCache{daemon: kwalletDaemons[0], app: "example", folder: "tokens"}
+	if err := cache.Save("KEY", &oauth2.Token{AccessToken: "ACCESS_TOKEN"}); err != nil {
+		t.Fatalf("Save error: %s", err)
+	}
+	if diff := cmp.Diff([]string{"kwallet-query"}, stdins); diff != "" {
+		t.Errorf("stdin mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestKDEWalletTokenCache_OpenError(t *testing.T) {
+	defer func(f func(string, string, ...string) (string, error)) { runCommand = f }(runCommand)
+	runCommand = func(stdin string, name string, args ...string) (string, error) {
+		if args[4] == "org.kde.KWallet.networkWallet" {
+			return "method return\n   string \"kdewallet\"\n", nil
+		}
+		// the user has denied the access
+		return "method return\n   int32 -1\n", nil
+	}
+	cache := &kdeWalletTokenCache{daemon: kwalletDaemons[0], app: "example", folder: "tokens"}
+	token, err := cache.Load("KEY")
+	if err == nil {
+		t.Fatalf("Load wants error but was %+v", token)
+	}
+	t.Logf("expected error: %s", err)
+}
+
+func testTokenCache(t *testing.T, cache TokenCache) {
+	token, err := cache.Load("KEY")
+	if err != nil {
+		t.Fatalf("Load error: %s", err)
+	}
+	if token != nil {
+		t.Errorf("Load wants nil but was %+v", token)
+	}
+
+	want := &oauth2.Token{
+		AccessToken:  "ACCESS_TOKEN",
+		TokenType:    "Bearer",
+		RefreshToken: "REFRESH_TOKEN",
+		Expiry:       time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	if err := cache.Save("KEY", want); err != nil {
+		t.Fatalf("Save error: %s", err)
+	}
+	got, err := cache.Load("KEY")
+	if err != nil {
+		t.Fatalf("Load error: %s", err)
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(oauth2.Token{})); diff != "" {
+		t.Errorf("token mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := cache.Remove("KEY"); err != nil {
+		t.Fatalf("Remove error: %s", err)
+	}
+	token, err = cache.Load("KEY")
+	if err != nil {
+		t.Fatalf("Load error: %s", err)
+	}
+	if token != nil {
+		t.Errorf("Load wants nil but was %+v", token)
+	}
+}
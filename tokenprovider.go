@@ -0,0 +1,91 @@
+package oauth2cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"golang.org/x/oauth2"
+)
+
+// TokenProvider provides a token by a strategy, such as the browser flow or a cache.
+type TokenProvider interface {
+	GetToken(ctx context.Context) (*oauth2.Token, error)
+}
+
+// BrowserFlowTokenProvider is a TokenProvider which performs GetToken with the config.
+type BrowserFlowTokenProvider struct {
+	Config Config
+}
+
+// GetToken performs the Authorization Code Grant Flow.
+func (p *BrowserFlowTokenProvider) GetToken(ctx context.Context) (*oauth2.Token, error) {
+	return GetToken(ctx, p.Config)
+}
+
+// DeviceFlowTokenProvider is a TokenProvider which performs GetTokenWithDeviceAuth with the config.
+type DeviceFlowTokenProvider struct {
+	OAuth2Config     oauth2.Config
+	DeviceAuthConfig DeviceAuthConfig
+}
+
+// GetToken performs the Device Authorization Grant.
+func (p *DeviceFlowTokenProvider) GetToken(ctx context.Context) (*oauth2.Token, error) {
+	return GetTokenWithDeviceAuth(ctx, p.OAuth2Config, p.DeviceAuthConfig)
+}
+
+// CachedTokenProvider is a TokenProvider which returns the token of the key in the cache.
+// If the token has expired, it refreshes the token by Config.OAuth2Config and saves the new token.
+// It returns an error if the cache has no token or the token could not be refreshed,
+// so that ChainedTokenProvider falls back to the next provider.
+type CachedTokenProvider struct {
+	Cache  TokenCache
+	Key    string
+	Config Config
+}
+
+// GetToken returns the cached token, or refreshes it.
+func (p *CachedTokenProvider) GetToken(ctx context.Context) (*oauth2.Token, error) {
+	token, err := p.Cache.Load(p.Key)
+	if err != nil {
+		return nil, fmt.Errorf("could not load the token from the cache: %w", err)
+	}
+	if token == nil {
+		return nil, errors.New("no token in the cache")
+	}
+	newToken, err := NewCachedTokenSource(ctx, p.Config, token).Token()
+	if err != nil {
+		return nil, err
+	}
+	if newToken != token {
+		if err := p.Cache.Save(p.Key, newToken); err != nil {
+			p.Config.logger().Warn("could not save the refreshed token to the cache", slog.Any("error", err))
+		}
+	}
+	return newToken, nil
+}
+
+// ChainedTokenProvider is a TokenProvider which tries the providers in order,
+// and returns the first token, e.g. a cache and then the browser flow.
+// If all providers failed, it returns the errors of them.
+type ChainedTokenProvider []TokenProvider
+
+// GetToken returns the token of the first successful provider.
+func (p ChainedTokenProvider) GetToken(ctx context.Context) (*oauth2.Token, error) {
+	if len(p) == 0 {
+		return nil, errors.New("no token provider")
+	}
+	var errs []error
+	for i, provider := range p {
+		token, err := provider.GetToken(ctx)
+		if err == nil {
+			return token, nil
+		}
+		errs = append(errs, fmt.Errorf("provider %d: %w", i, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, fmt.Errorf("could not get a token from any provider: %w", errors.Join(errs...))
+}
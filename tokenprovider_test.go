@@ -0,0 +1,110 @@
+package oauth2cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+type tokenProviderFunc func(ctx context.Context) (*oauth2.Token, error)
+
+func (f tokenProviderFunc) GetToken(ctx context.Context) (*oauth2.Token, error) { return f(ctx) }
+
+type memoryTokenCache map[string]*oauth2.Token
+
+func (c memoryTokenCache) Load(key string) (*oauth2.Token, error) { return c[key], nil }
+func (c memoryTokenCache) Save(key string, token *oauth2.Token) error {
+	c[key] = token
+	return nil
+}
+func (c memoryTokenCache) Remove(key string) error {
+	delete(c, key)
+	return nil
+}
+
+func TestCachedTokenProvider(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, `{"access_token":"NEW_ACCESS_TOKEN","token_type":"Bearer","expires_in":3600}`)
+	}))
+	defer s.Close()
+	cfg := Config{OAuth2Config: oauth2.Config{ClientID: "YOUR_CLIENT_ID", Endpoint: oauth2.Endpoint{TokenURL: s.URL}}}
+
+	t.Run("NotFound", func(t *testing.T) {
+		p := &CachedTokenProvider{Cache: memoryTokenCache{}, Key: "KEY", Config: cfg}
+		if _, err := p.GetToken(context.TODO()); err == nil {
+			t.Errorf("GetToken wants error but was nil")
+		}
+	})
+	t.Run("Valid", func(t *testing.T) {
+		token := &oauth2.Token{AccessToken: "ACCESS_TOKEN", Expiry: time.Now().Add(time.Hour)}
+		p := &CachedTokenProvider{Cache: memoryTokenCache{"KEY": token}, Key: "KEY", Config: cfg}
+		got, err := p.GetToken(context.TODO())
+		if err != nil {
+			t.Fatalf("GetToken error: %s", err)
+		}
+		if got != token {
+			t.Errorf("GetToken wants the cached token but was %+v", got)
+		}
+	})
+	t.Run("Refreshed", func(t *testing.T) {
+		cache := memoryTokenCache{"KEY": {AccessToken: "ACCESS_TOKEN", RefreshToken: "REFRESH_TOKEN", Expiry: time.Now().Add(-time.Minute)}}
+		p := &CachedTokenProvider{Cache: cache, Key: "KEY", Config: cfg}
+		got, err := p.GetToken(context.TODO())
+		if err != nil {
+			t.Fatalf("GetToken error: %s", err)
+		}
+		if w := "NEW_ACCESS_TOKEN"; got.AccessToken != w {
+			t.Errorf("AccessToken wants %s but was %s", w, got.AccessToken)
+		}
+		if cache["KEY"] != got {
+			t.Errorf("cache wants the new token but was %+v", cache["KEY"])
+		}
+	})
+}
+
+func TestChainedTokenProvider(t *testing.T) {
+	token := &oauth2.Token{AccessToken: "ACCESS_TOKEN"}
+	failing := tokenProviderFunc(func(context.Context) (*oauth2.Token, error) { return nil, errors.New("FAILED") })
+	succeeding := tokenProviderFunc(func(context.Context) (*oauth2.Token, error) { return token, nil })
+
+	t.Run("Fallback", func(t *testing.T) {
+		var called bool
+		notCalled := tokenProviderFunc(func(context.Context) (*oauth2.Token, error) {
+			called = true
+			return nil, nil
+		})
+		got, err := ChainedTokenProvider{failing, succeeding, notCalled}.GetToken(context.TODO())
+		if err != nil {
+			t.Fatalf("GetToken error: %s", err)
+		}
+		if got != token {
+			t.Errorf("GetToken wants the token but was %+v", got)
+		}
+		if called {
+			t.Errorf("provider after the first success should not be called")
+		}
+	})
+	t.Run("AllFailed", func(t *testing.T) {
+		if _, err := (ChainedTokenProvider{failing, failing}).GetToken(context.TODO()); err == nil {
+			t.Errorf("GetToken wants error but was nil")
+		}
+	})
+	t.Run("ContextDone", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.TODO())
+		canceling := tokenProviderFunc(func(context.Context) (*oauth2.Token, error) {
+			cancel()
+			return nil, context.Canceled
+		})
+		_, err := ChainedTokenProvider{canceling, succeeding}.GetToken(ctx)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("GetToken wants context.Canceled but was %v", err)
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package oauth2cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/int128/oauth2cli/internal/jose"
+	"golang.org/x/oauth2"
+)
+
+const tokenResponseJWTBodyLimit = 1 << 20
+
+// TokenResponseJWTVerificationError represents an error of the verification
+// of the token response signed as a JWT.
+type TokenResponseJWTVerificationError struct {
+	Err error
+}
+
+func (e *TokenResponseJWTVerificationError) Error() string {
+	return fmt.Sprintf("could not verify the token response JWT: %s", e.Err)
+}
+
+func (e *TokenResponseJWTVerificationError) Unwrap() error { return e.Err }
+
+// withTokenResponseJWT returns a context which has an HTTP client
+// to decode the token response of Content-Type application/jwt, if Config.TokenResponseJWT is set.
+// The audience of the JWT must be the client ID.
+func withTokenResponseJWT(ctx context.Context, c *Config, clientID string) context.Context {
+	if !c.TokenResponseJWT {
+		return ctx
+	}
+	base := contextClient(ctx)
+	client := *base
+	client.Transport = &tokenResponseJWTTransport{
+		base:     base,
+		jwksURL:  c.TokenResponseJWKSURL,
+		issuer:   c.TokenResponseJWTIssuer,
+		audience: clientID,
+	}
+	return context.WithValue(ctx, oauth2.HTTPClient, &client)
+}
+
+// tokenResponseJWTTransport verifies a token response signed as a JWT by the JWKS,
+// and replaces it with the JSON of the claims.
+type tokenResponseJWTTransport struct {
+	base     *http.Client
+	jwksURL  string
+	issuer   string
+	audience string
+}
+
+func (t *tokenResponseJWTTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.base.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if !isJWTContentType(resp.Header.Get("Content-Type")) {
+		return resp, nil
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, tokenResponseJWTBodyLimit))
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("could not read the token response: %w", err)
+	}
+	jwks, err := jose.FetchJWKS(req.Context(), t.base, t.jwksURL)
+	if err != nil {
+		return nil, &TokenResponseJWTVerificationError{Err: fmt.Errorf("could not fetch the JWKS: %w", err)}
+	}
+	claims, err := jose.Verify(strings.TrimSpace(string(b)), jwks)
+	if err != nil {
+		return nil, &TokenResponseJWTVerificationError{Err: err}
+	}
+	if _, err := jose.ValidateClaims(claims, t.issuer, t.audience, time.Now()); err != nil {
+		return nil, &TokenResponseJWTVerificationError{Err: err}
+	}
+	body, err := json.Marshal(claims)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode the claims: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Type", "application/json")
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return resp, nil
+}
+
+func isJWTContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/jwt" || mediaType == "application/token-introspection+jwt"
+}
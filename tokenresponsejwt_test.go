@@ -0,0 +1,160 @@
+package oauth2cli
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/int128/oauth2cli/internal/jose"
+	"golang.org/x/oauth2"
+)
+
+func TestExchangeCode_TokenResponseJWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("could not generate a key: %s", err)
+	}
+	anotherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("could not generate a key: %s", err)
+	}
+	newClaims := func(mutate func(claims map[string]interface{})) map[string]interface{} {
+		claims := map[string]interface{}{
+			"iss":          "https://issuer.example.com",
+			"aud":          "YOUR_CLIENT_ID",
+			"exp":          time.Now().Add(time.Minute).Unix(),
+			"access_token": "ACCESS_TOKEN",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		}
+		if mutate != nil {
+			mutate(claims)
+		}
+		return claims
+	}
+	claims := newClaims(nil)
+	mux := http.NewServeMux()
+	s := httptest.NewServer(mux)
+	defer s.Close()
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "KEY_ID",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/jwt")
+		_, _ = w.Write([]byte(signTestJWT(t, key, claims)))
+	})
+	mux.HandleFunc("/token-invalid", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/jwt")
+		_, _ = w.Write([]byte(signTestJWT(t, anotherKey, claims)))
+	})
+	mux.HandleFunc("/token-expired", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/jwt")
+		_, _ = w.Write([]byte(signTestJWT(t, key, newClaims(func(claims map[string]interface{}) {
+			claims["exp"] = time.Now().Add(-time.Minute).Unix()
+		}))))
+	})
+	mux.HandleFunc("/token-wrong-audience", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/jwt")
+		_, _ = w.Write([]byte(signTestJWT(t, key, newClaims(func(claims map[string]interface{}) {
+			claims["aud"] = "ANOTHER_CLIENT_ID"
+		}))))
+	})
+	mux.HandleFunc("/token-wrong-issuer", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/jwt")
+		_, _ = w.Write([]byte(signTestJWT(t, key, newClaims(func(claims map[string]interface{}) {
+			claims["iss"] = "https://evil.example.com"
+		}))))
+	})
+	mux.HandleFunc("/token-json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(claims)
+	})
+
+	for _, c := range []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"Verified", "/token", false},
+		{"JSON", "/token-json", false},
+		{"InvalidSignature", "/token-invalid", true},
+		{"Expired", "/token-expired", true},
+		{"WrongAudience", "/token-wrong-audience", true},
+		{"WrongIssuer", "/token-wrong-issuer", true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := Config{
+				TokenResponseJWT:       true,
+				TokenResponseJWKSURL:   s.URL + "/jwks",
+				TokenResponseJWTIssuer: "https://issuer.example.com",
+			}
+			oauth2Config := &oauth2.Config{
+				ClientID: "YOUR_CLIENT_ID",
+				Endpoint: oauth2.Endpoint{TokenURL: s.URL + c.path, AuthStyle: oauth2.AuthStyleInParams},
+			}
+			token, err := exchangeCode(context.TODO(), &cfg, oauth2Config, "AUTH_CODE")
+			if c.wantErr {
+				var verificationErr *TokenResponseJWTVerificationError
+				if !errors.As(err, &verificationErr) {
+					t.Errorf("exchangeCode wants TokenResponseJWTVerificationError but was %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("exchangeCode error: %s", err)
+			}
+			if w := "ACCESS_TOKEN"; token.AccessToken != w {
+				t.Errorf("AccessToken wants %s but was %s", w, token.AccessToken)
+			}
+		})
+	}
+
+	t.Run("NoJWKSURL", func(t *testing.T) {
+		cfg := Config{TokenResponseJWT: true, TokenResponseJWTIssuer: "https://issuer.example.com"}
+		if err := cfg.validateAndSetDefaults(); err == nil {
+			t.Errorf("validateAndSetDefaults wants error but was nil")
+		}
+	})
+	t.Run("NoIssuer", func(t *testing.T) {
+		cfg := Config{TokenResponseJWT: true, TokenResponseJWKSURL: s.URL + "/jwks"}
+		if err := cfg.validateAndSetDefaults(); err == nil {
+			t.Errorf("validateAndSetDefaults wants error but was nil")
+		}
+	})
+}
+
+func signTestJWT(t *testing.T, key *rsa.PrivateKey, claims map[string]interface{}) string {
+	header, err := json.Marshal(jose.Header{Alg: "RS256", Kid: "KEY_ID"})
+	if err != nil {
+		t.Fatalf("could not encode the header: %s", err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("could not encode the claims: %s", err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("could not sign: %s", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
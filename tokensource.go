@@ -0,0 +1,81 @@
+package oauth2cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// CachedTokenSource is an oauth2.TokenSource which reuses the token until it expires,
+// and then refreshes it using the refresh token.
+//
+// It calls Config.OnTokenRefreshed or Config.OnTokenRefreshFailed on each refresh.
+// It is safe for concurrent use.
+type CachedTokenSource struct {
+	ctx    context.Context
+	config Config
+
+	mu    sync.Mutex
+	token *oauth2.Token
+}
+
+// NewCachedTokenSource returns a CachedTokenSource which starts with the token,
+// such as a token returned by GetToken.
+// The context is used for refreshing the token.
+func NewCachedTokenSource(ctx context.Context, config Config, token *oauth2.Token) *CachedTokenSource {
+	return &CachedTokenSource{ctx: ctx, config: config, token: token}
+}
+
+// Token returns the current token if it is valid, or refreshes it.
+// The token is regarded as expired Config.TokenExpirySlack before the expiry.
+func (s *CachedTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	oldToken := s.token
+	if isTokenFresh(oldToken, s.config.tokenExpirySlack()) {
+		s.mu.Unlock()
+		return oldToken, nil
+	}
+	newToken, err := s.refresh(oldToken)
+	if err == nil {
+		s.token = newToken
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		if s.config.OnTokenRefreshFailed != nil {
+			s.config.OnTokenRefreshFailed(s.ctx, oldToken, err)
+		}
+		return nil, err
+	}
+	if s.config.OnTokenRefreshed != nil {
+		s.config.OnTokenRefreshed(s.ctx, oldToken, newToken)
+	}
+	return newToken, nil
+}
+
+func (s *CachedTokenSource) refresh(token *oauth2.Token) (*oauth2.Token, error) {
+	if token == nil || token.RefreshToken == "" {
+		return nil, errors.New("token has expired and has no refresh token")
+	}
+	newToken, err := s.config.OAuth2Config.TokenSource(s.ctx, &oauth2.Token{RefreshToken: token.RefreshToken}).Token()
+	if err != nil {
+		return nil, fmt.Errorf("could not refresh the token: %w", err)
+	}
+	return newToken, nil
+}
+
+// isTokenFresh returns true if the token has an access token
+// which does not expire within the slack.
+func isTokenFresh(token *oauth2.Token, slack time.Duration) bool {
+	if token == nil || token.AccessToken == "" {
+		return false
+	}
+	if token.Expiry.IsZero() {
+		return true
+	}
+	return time.Until(token.Expiry) >= slack
+}
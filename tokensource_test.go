@@ -0,0 +1,110 @@
+package oauth2cli
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestCachedTokenSource(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.FormValue("refresh_token") != "REFRESH_TOKEN" {
+			w.WriteHeader(400)
+			_, _ = fmt.Fprint(w, `{"error":"invalid_grant"}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"access_token":"NEW_ACCESS_TOKEN","token_type":"Bearer","expires_in":3600}`)
+	}))
+	defer s.Close()
+
+	t.Run("Valid", func(t *testing.T) {
+		token := &oauth2.Token{AccessToken: "ACCESS_TOKEN", Expiry: time.Now().Add(time.Hour)}
+		cfg := Config{
+			OnTokenRefreshed: func(context.Context, *oauth2.Token, *oauth2.Token) {
+				t.Errorf("OnTokenRefreshed should not be called")
+			},
+		}
+		got, err := NewCachedTokenSource(context.TODO(), cfg, token).Token()
+		if err != nil {
+			t.Fatalf("Token error: %s", err)
+		}
+		if got != token {
+			t.Errorf("Token wants the current token but was %+v", got)
+		}
+	})
+
+	t.Run("Refreshed", func(t *testing.T) {
+		token := &oauth2.Token{AccessToken: "ACCESS_TOKEN", RefreshToken: "REFRESH_TOKEN", Expiry: time.Now().Add(-time.Minute)}
+		var refreshed *oauth2.Token
+		cfg := Config{
+			OAuth2Config: oauth2.Config{ClientID: "YOUR_CLIENT_ID", Endpoint: oauth2.Endpoint{TokenURL: s.URL}},
+			OnTokenRefreshed: func(_ context.Context, oldToken, newToken *oauth2.Token) {
+				if oldToken != token {
+					t.Errorf("oldToken wants the expired token but was %+v", oldToken)
+				}
+				refreshed = newToken
+			},
+		}
+		ts := NewCachedTokenSource(context.TODO(), cfg, token)
+		got, err := ts.Token()
+		if err != nil {
+			t.Fatalf("Token error: %s", err)
+		}
+		if w := "NEW_ACCESS_TOKEN"; got.AccessToken != w {
+			t.Errorf("AccessToken wants %s but was %s", w, got.AccessToken)
+		}
+		if refreshed != got {
+			t.Errorf("OnTokenRefreshed wants the new token but was %+v", refreshed)
+		}
+		if got2, _ := ts.Token(); got2 != got {
+			t.Errorf("Token wants the cached token but was %+v", got2)
+		}
+	})
+
+	t.Run("RefreshFailed", func(t *testing.T) {
+		token := &oauth2.Token{AccessToken: "ACCESS_TOKEN", RefreshToken: "INVALID", Expiry: time.Now().Add(-time.Minute)}
+		var failed error
+		cfg := Config{
+			OAuth2Config: oauth2.Config{ClientID: "YOUR_CLIENT_ID", Endpoint: oauth2.Endpoint{TokenURL: s.URL}},
+			OnTokenRefreshFailed: func(_ context.Context, _ *oauth2.Token, err error) {
+				failed = err
+			},
+		}
+		_, err := NewCachedTokenSource(context.TODO(), cfg, token).Token()
+		if err == nil {
+			t.Fatalf("Token wants error but was nil")
+		}
+		if failed == nil {
+			t.Errorf("OnTokenRefreshFailed was not called")
+		}
+	})
+}
+
+func TestIsTokenFresh(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		token *oauth2.Token
+		slack time.Duration
+		want  bool
+	}{
+		{"Nil", nil, 0, false},
+		{"NoAccessToken", &oauth2.Token{}, 0, false},
+		{"NoExpiry", &oauth2.Token{AccessToken: "ACCESS_TOKEN"}, 10 * time.Second, true},
+		{"BeyondSlack", &oauth2.Token{AccessToken: "ACCESS_TOKEN", Expiry: time.Now().Add(time.Minute)}, 10 * time.Second, true},
+		{"WithinSlack", &oauth2.Token{AccessToken: "ACCESS_TOKEN", Expiry: time.Now().Add(5 * time.Second)}, 10 * time.Second, false},
+		{"WithinLargeSlack", &oauth2.Token{AccessToken: "ACCESS_TOKEN", Expiry: time.Now().Add(time.Minute)}, 5 * time.Minute, false},
+		{"NoSlack", &oauth2.Token{AccessToken: "ACCESS_TOKEN", Expiry: time.Now().Add(5 * time.Second)}, 0, true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if got := isTokenFresh(c.token, c.slack); got != c.want {
+				t.Errorf("isTokenFresh wants %v but was %v", c.want, got)
+			}
+		})
+	}
+}
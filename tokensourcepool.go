@@ -0,0 +1,168 @@
+package oauth2cli
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// TokenSourcePool holds token sources by key, e.g. a CachedTokenSource per user.
+// If the pool is full, the least recently used source is evicted.
+// It is safe for concurrent use.
+// The zero value is an unlimited pool without the TTL.
+type TokenSourcePool struct {
+	maxSize int
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // front is the most recently used
+	stop    chan struct{}
+	stopped sync.Once
+}
+
+type tokenSourcePoolEntry struct {
+	key      string
+	source   oauth2.TokenSource
+	lastUsed time.Time
+}
+
+// NewTokenSourcePool returns a TokenSourcePool which holds up to maxSize sources.
+// If maxSize is 0, the pool is unlimited.
+//
+// If ttl is positive, a source which has not been used for the ttl expires,
+// and a background goroutine removes the expired sources periodically.
+// Note that the ttl is the idle time of a source, not the expiry of its token.
+// You need to call Close to stop the goroutine.
+func NewTokenSourcePool(maxSize int, ttl time.Duration) *TokenSourcePool {
+	p := &TokenSourcePool{
+		maxSize: maxSize,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		stop:    make(chan struct{}),
+	}
+	if ttl > 0 {
+		go p.cleanupLoop()
+	}
+	return p
+}
+
+// GetOrCreate returns the source of the key.
+// If the pool does not have it, this calls createFn and adds the source to the pool.
+// createFn is called without the lock, so it may perform a flow such as GetToken.
+func (p *TokenSourcePool) GetOrCreate(key string, createFn func() (oauth2.TokenSource, error)) (oauth2.TokenSource, error) {
+	if ts := p.get(key); ts != nil {
+		return ts, nil
+	}
+	ts, err := createFn()
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lazyInit()
+	if e, ok := p.entries[key]; ok {
+		// another call has created it
+		return p.touch(e), nil
+	}
+	p.entries[key] = p.lru.PushFront(&tokenSourcePoolEntry{key: key, source: ts, lastUsed: time.Now()})
+	for p.maxSize > 0 && p.lru.Len() > p.maxSize {
+		p.removeElement(p.lru.Back())
+	}
+	return ts, nil
+}
+
+func (p *TokenSourcePool) get(key string) oauth2.TokenSource {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lazyInit()
+	if e, ok := p.entries[key]; ok {
+		return p.touch(e)
+	}
+	return nil
+}
+
+func (p *TokenSourcePool) touch(e *list.Element) oauth2.TokenSource {
+	entry := e.Value.(*tokenSourcePoolEntry)
+	entry.lastUsed = time.Now()
+	p.lru.MoveToFront(e)
+	return entry.source
+}
+
+// Remove removes the source of the key.
+// It does nothing if the pool does not have it.
+func (p *TokenSourcePool) Remove(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lazyInit()
+	if e, ok := p.entries[key]; ok {
+		p.removeElement(e)
+	}
+}
+
+// Len returns the number of the sources in the pool.
+func (p *TokenSourcePool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lazyInit()
+	return p.lru.Len()
+}
+
+// Close stops the background goroutine.
+func (p *TokenSourcePool) Close() {
+	p.stopped.Do(func() {
+		if p.stop != nil {
+			close(p.stop)
+		}
+	})
+}
+
+// lazyInit initializes the zero value.
+// The caller must hold the lock.
+func (p *TokenSourcePool) lazyInit() {
+	if p.entries == nil {
+		p.entries = make(map[string]*list.Element)
+		p.lru = list.New()
+	}
+}
+
+func (p *TokenSourcePool) removeElement(e *list.Element) {
+	p.lru.Remove(e)
+	delete(p.entries, e.Value.(*tokenSourcePoolEntry).key)
+}
+
+// minTokenSourcePoolCleanupInterval is the lower bound of the cleanup interval,
+// because a ticker requires a positive interval.
+const minTokenSourcePoolCleanupInterval = time.Millisecond
+
+func (p *TokenSourcePool) cleanupLoop() {
+	interval := p.ttl / 2
+	if interval < minTokenSourcePoolCleanupInterval {
+		interval = minTokenSourcePoolCleanupInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.removeExpired(time.Now())
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+func (p *TokenSourcePool) removeExpired(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// the back is the least recently used
+	for e := p.lru.Back(); e != nil; e = p.lru.Back() {
+		if now.Sub(e.Value.(*tokenSourcePoolEntry).lastUsed) < p.ttl {
+			return
+		}
+		p.removeElement(e)
+	}
+}
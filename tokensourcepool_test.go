@@ -0,0 +1,105 @@
+package oauth2cli
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func staticTokenSource(accessToken string) func() (oauth2.TokenSource, error) {
+	return func() (oauth2.TokenSource, error) {
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: accessToken}), nil
+	}
+}
+
+func TestTokenSourcePool(t *testing.T) {
+	t.Run("GetOrCreate", func(t *testing.T) {
+		p := NewTokenSourcePool(0, 0)
+		defer p.Close()
+		ts1, err := p.GetOrCreate("alice", staticTokenSource("ALICE"))
+		if err != nil {
+			t.Fatalf("GetOrCreate error: %s", err)
+		}
+		ts2, err := p.GetOrCreate("alice", func() (oauth2.TokenSource, error) {
+			t.Errorf("createFn should not be called")
+			return nil, nil
+		})
+		if err != nil {
+			t.Fatalf("GetOrCreate error: %s", err)
+		}
+		if ts1 != ts2 {
+			t.Errorf("GetOrCreate wants the same source")
+		}
+		if _, err := p.GetOrCreate("bob", func() (oauth2.TokenSource, error) { return nil, errors.New("FAILED") }); err == nil {
+			t.Errorf("GetOrCreate wants error but was nil")
+		}
+		if p.Len() != 1 {
+			t.Errorf("Len wants 1 but was %d", p.Len())
+		}
+		p.Remove("alice")
+		if p.Len() != 0 {
+			t.Errorf("Len wants 0 but was %d", p.Len())
+		}
+	})
+
+	t.Run("EvictLRU", func(t *testing.T) {
+		p := NewTokenSourcePool(2, 0)
+		defer p.Close()
+		for _, key := range []string{"alice", "bob", "alice", "carol"} {
+			if _, err := p.GetOrCreate(key, staticTokenSource(key)); err != nil {
+				t.Fatalf("GetOrCreate error: %s", err)
+			}
+		}
+		// bob is the least recently used
+		var created bool
+		_, _ = p.GetOrCreate("bob", func() (oauth2.TokenSource, error) {
+			created = true
+			return staticTokenSource("bob")()
+		})
+		if !created {
+			t.Errorf("bob wants to be evicted")
+		}
+		if p.Len() != 2 {
+			t.Errorf("Len wants 2 but was %d", p.Len())
+		}
+	})
+
+	t.Run("RemoveExpired", func(t *testing.T) {
+		p := NewTokenSourcePool(0, 20*time.Millisecond)
+		defer p.Close()
+		if _, err := p.GetOrCreate("alice", staticTokenSource("ALICE")); err != nil {
+			t.Fatalf("GetOrCreate error: %s", err)
+		}
+		deadline := time.Now().Add(time.Second)
+		for p.Len() > 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if p.Len() != 0 {
+			t.Errorf("Len wants 0 but was %d", p.Len())
+		}
+	})
+	t.Run("TinyTTL", func(t *testing.T) {
+		p := NewTokenSourcePool(0, time.Nanosecond)
+		defer p.Close()
+		if _, err := p.GetOrCreate("alice", staticTokenSource("ALICE")); err != nil {
+			t.Fatalf("GetOrCreate error: %s", err)
+		}
+	})
+
+	t.Run("ZeroValue", func(t *testing.T) {
+		var p TokenSourcePool
+		defer p.Close()
+		if _, err := p.GetOrCreate("alice", staticTokenSource("ALICE")); err != nil {
+			t.Fatalf("GetOrCreate error: %s", err)
+		}
+		if p.Len() != 1 {
+			t.Errorf("Len wants 1 but was %d", p.Len())
+		}
+		p.Remove("alice")
+		if p.Len() != 0 {
+			t.Errorf("Len wants 0 but was %d", p.Len())
+		}
+	})
+}
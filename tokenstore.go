@@ -0,0 +1,76 @@
+package oauth2cli
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// InMemoryTokenStore is a TokenCache which stores the tokens in memory.
+// It is safe for concurrent use, e.g. goroutines which need independent tokens.
+// The zero value is ready to use.
+type InMemoryTokenStore struct {
+	m sync.Map
+}
+
+// Get returns the token of the key.
+func (s *InMemoryTokenStore) Get(key string) (*oauth2.Token, bool) {
+	v, ok := s.m.Load(key)
+	if !ok {
+		return nil, false
+	}
+	return v.(*oauth2.Token), true
+}
+
+// Set stores the token of the key.
+func (s *InMemoryTokenStore) Set(key string, t *oauth2.Token) {
+	s.m.Store(key, t)
+}
+
+// Delete removes the token of the key.
+func (s *InMemoryTokenStore) Delete(key string) {
+	s.m.Delete(key)
+}
+
+// Range calls f for each token until f returns false.
+func (s *InMemoryTokenStore) Range(f func(key string, t *oauth2.Token) bool) {
+	s.m.Range(func(k, v interface{}) bool {
+		return f(k.(string), v.(*oauth2.Token))
+	})
+}
+
+// GarbageCollect removes the expired tokens and returns the number of them.
+// A token without expiry is never removed.
+func (s *InMemoryTokenStore) GarbageCollect() int {
+	now := time.Now()
+	var n int
+	s.m.Range(func(k, v interface{}) bool {
+		t := v.(*oauth2.Token)
+		if !t.Expiry.IsZero() && t.Expiry.Before(now) {
+			if s.m.CompareAndDelete(k, v) {
+				n++
+			}
+		}
+		return true
+	})
+	return n
+}
+
+// Load implements TokenCache.
+func (s *InMemoryTokenStore) Load(key string) (*oauth2.Token, error) {
+	t, _ := s.Get(key)
+	return t, nil
+}
+
+// Save implements TokenCache.
+func (s *InMemoryTokenStore) Save(key string, token *oauth2.Token) error {
+	s.Set(key, token)
+	return nil
+}
+
+// Remove implements TokenCache.
+func (s *InMemoryTokenStore) Remove(key string) error {
+	s.Delete(key)
+	return nil
+}
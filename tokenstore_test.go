@@ -0,0 +1,52 @@
+package oauth2cli
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestInMemoryTokenStore(t *testing.T) {
+	var s InMemoryTokenStore
+	if _, ok := s.Get("KEY"); ok {
+		t.Errorf("Get wants not found")
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s.Set(fmt.Sprintf("KEY%d", i), &oauth2.Token{AccessToken: "ACCESS_TOKEN"})
+		}(i)
+	}
+	wg.Wait()
+	var n int
+	s.Range(func(string, *oauth2.Token) bool {
+		n++
+		return true
+	})
+	if n != 10 {
+		t.Errorf("Range wants 10 tokens but was %d", n)
+	}
+	s.Delete("KEY0")
+	if _, ok := s.Get("KEY0"); ok {
+		t.Errorf("Get wants not found after Delete")
+	}
+
+	var cache TokenCache = &s
+	if err := cache.Save("KEY", &oauth2.Token{AccessToken: "EXPIRED", Expiry: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatalf("Save error: %s", err)
+	}
+	if token, err := cache.Load("KEY"); err != nil || token.AccessToken != "EXPIRED" {
+		t.Errorf("Load wants the token but was %+v, %v", token, err)
+	}
+	if got := s.GarbageCollect(); got != 1 {
+		t.Errorf("GarbageCollect wants 1 but was %d", got)
+	}
+	if token, _ := cache.Load("KEY"); token != nil {
+		t.Errorf("Load wants nil after GarbageCollect but was %+v", token)
+	}
+}
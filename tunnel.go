@@ -0,0 +1,156 @@
+package oauth2cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sync"
+)
+
+// TunnelProvider exposes the local server to the internet.
+// This is useful if the browser runs on another machine, such as a remote development environment.
+type TunnelProvider interface {
+	// Start starts a tunnel to the local address, e.g. 127.0.0.1:8000.
+	// It returns the public URL of the tunnel.
+	Start(ctx context.Context, localAddr string) (publicURL string, err error)
+	// Stop stops the tunnel.
+	Stop() error
+}
+
+// NewNgrokTunnel returns a TunnelProvider which runs the ngrok command.
+// See https://ngrok.com/docs/agent/
+// If authToken is empty, the configuration of ngrok is used.
+func NewNgrokTunnel(authToken string) TunnelProvider {
+	return &commandTunnel{
+		name: "ngrok",
+		args: func(localAddr string) []string {
+			args := []string{"http", localAddr, "--log", "stdout", "--log-format", "json"}
+			if authToken != "" {
+				args = append(args, "--authtoken", authToken)
+			}
+			return args
+		},
+		parseURL: parseNgrokLog,
+	}
+}
+
+func parseNgrokLog(line string) string {
+	var entry struct {
+		Msg string `json:"msg"`
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		return ""
+	}
+	if entry.Msg != "started tunnel" {
+		return ""
+	}
+	return entry.URL
+}
+
+// NewLocalhostRunTunnel returns a TunnelProvider which runs the ssh command to localhost.run.
+// See https://localhost.run
+func NewLocalhostRunTunnel() TunnelProvider {
+	return &commandTunnel{
+		name: "ssh",
+		args: func(localAddr string) []string {
+			return []string{
+				"-o", "StrictHostKeyChecking=accept-new",
+				"-o", "ServerAliveInterval=30",
+				"-T",
+				"-R", "80:" + localAddr,
+				"nokey@localhost.run",
+			}
+		},
+		parseURL: parseLocalhostRunOutput,
+	}
+}
+
+var localhostRunURLPattern = regexp.MustCompile(`https://[0-9A-Za-z.-]+\.(lhr\.life|localhost\.run)`)
+
+func parseLocalhostRunOutput(line string) string {
+	return localhostRunURLPattern.FindString(line)
+}
+
+// commandTunnel runs a command which prints the public URL of the tunnel.
+type commandTunnel struct {
+	name     string
+	args     func(localAddr string) []string
+	parseURL func(line string) string // returns empty if the line has no URL
+
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	stdout chan struct{} // closed when the output is read to the end
+}
+
+func (t *commandTunnel) Start(ctx context.Context, localAddr string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cmd != nil {
+		return "", errors.New("tunnel is already started")
+	}
+	// the command must live after ctx of Start
+	cmd := exec.Command(t.name, t.args(localAddr)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", fmt.Errorf("could not open stdout of %s: %w", t.name, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("could not start %s: %w", t.name, err)
+	}
+	t.cmd = cmd
+	t.stdout = make(chan struct{})
+
+	urlCh := make(chan string, 1)
+	go func() {
+		defer close(t.stdout)
+		scanner := bufio.NewScanner(stdout)
+		var found bool
+		for scanner.Scan() {
+			if found {
+				continue
+			}
+			if u := t.parseURL(scanner.Text()); u != "" {
+				found = true
+				urlCh <- u
+			}
+		}
+		close(urlCh)
+	}()
+	select {
+	case u, ok := <-urlCh:
+		if ok {
+			return u, nil
+		}
+		t.stop()
+		return "", fmt.Errorf("%s exited without the public URL", t.name)
+	case <-ctx.Done():
+		t.stop()
+		return "", fmt.Errorf("context done while waiting for %s: %w", t.name, ctx.Err())
+	}
+}
+
+func (t *commandTunnel) Stop() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stop()
+}
+
+func (t *commandTunnel) stop() error {
+	if t.cmd == nil {
+		return nil
+	}
+	cmd := t.cmd
+	t.cmd = nil
+	// an error is returned if the command has already exited
+	killErr := cmd.Process.Kill()
+	<-t.stdout
+	if err := cmd.Wait(); err != nil && killErr != nil {
+		return fmt.Errorf("%s exited with error: %w", t.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package oauth2cli
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCommandTunnel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.TODO(), 1*time.Second)
+	defer cancel()
+
+	t.Run("Started", func(t *testing.T) {
+		tunnel := &commandTunnel{
+			name: "sh",
+			args: func(localAddr string) []string {
+				return []string{"-c", `echo '{"msg":"started tunnel","url":"https://example.ngrok.app","addr":"http://` + localAddr + `"}'; exec sleep 10`}
+			},
+			parseURL: parseNgrokLog,
+		}
+		publicURL, err := tunnel.Start(ctx, "127.0.0.1:8000")
+		if err != nil {
+			t.Fatalf("Start error: %s", err)
+		}
+		if w := "https://example.ngrok.app"; publicURL != w {
+			t.Errorf("publicURL wants %s but was %s", w, publicURL)
+		}
+		if err := tunnel.Stop(); err != nil {
+			t.Errorf("Stop error: %s", err)
+		}
+	})
+
+	t.Run("ExitedWithoutURL", func(t *testing.T) {
+		tunnel := &commandTunnel{
+			name:     "sh",
+			args:     func(string) []string { return []string{"-c", "echo hello"} },
+			parseURL: parseNgrokLog,
+		}
+		if _, err := tunnel.Start(ctx, "127.0.0.1:8000"); err == nil {
+			t.Errorf("Start wants error but was nil")
+		}
+	})
+}
+
+func TestParseLocalhostRunOutput(t *testing.T) {
+	for _, c := range []struct {
+		line string
+		want string
+	}{
+		{"abcdef0123.lhr.life tunneled with tls termination, https://abcdef0123.lhr.life", "https://abcdef0123.lhr.life"},
+		{"Welcome to localhost.run!", ""},
+	} {
+		if got := parseLocalhostRunOutput(c.line); got != c.want {
+			t.Errorf("parseLocalhostRunOutput(%q) wants %q but was %q", c.line, c.want, got)
+		}
+	}
+}
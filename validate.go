@@ -0,0 +1,62 @@
+package oauth2cli
+
+import "golang.org/x/oauth2"
+
+// Severity represents a level of ConfigWarning.
+type Severity int
+
+// Levels of Severity.
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "Info"
+	case SeverityWarning:
+		return "Warning"
+	case SeverityError:
+		return "Error"
+	}
+	return "Unknown"
+}
+
+// ConfigWarning represents a problem of the config.
+type ConfigWarning struct {
+	// Name of the field, e.g. ClientID.
+	Field    string
+	Message  string
+	Severity Severity
+}
+
+// ValidateOAuth2Config checks whether the config is suitable for the Authorization Code Flow.
+// This is useful if you build the config from user input.
+// It returns nil if no problem is found.
+//
+// An empty client secret is reported as a warning,
+// because oauth2.Config does not tell whether PKCE is enabled.
+func ValidateOAuth2Config(cfg oauth2.Config) []ConfigWarning {
+	var warnings []ConfigWarning
+	if cfg.ClientID == "" {
+		warnings = append(warnings, ConfigWarning{"ClientID", "client ID is empty", SeverityError})
+	}
+	if cfg.Endpoint.AuthURL == "" {
+		warnings = append(warnings, ConfigWarning{"Endpoint.AuthURL", "authorization endpoint is empty", SeverityError})
+	}
+	if cfg.Endpoint.TokenURL == "" {
+		warnings = append(warnings, ConfigWarning{"Endpoint.TokenURL", "token endpoint is empty", SeverityError})
+	}
+	if cfg.RedirectURL != "" {
+		warnings = append(warnings, ConfigWarning{"RedirectURL", "redirect URL will be overridden by the local server", SeverityWarning})
+	}
+	if cfg.ClientSecret == "" {
+		warnings = append(warnings, ConfigWarning{"ClientSecret", "client secret is empty, PKCE should be enabled for a public client", SeverityWarning})
+	}
+	if len(cfg.Scopes) == 0 {
+		warnings = append(warnings, ConfigWarning{"Scopes", "no scope is requested", SeverityInfo})
+	}
+	return warnings
+}
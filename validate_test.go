@@ -0,0 +1,42 @@
+package oauth2cli
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/oauth2"
+)
+
+func TestValidateOAuth2Config(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		warnings := ValidateOAuth2Config(oauth2.Config{
+			ClientID:     "YOUR_CLIENT_ID",
+			ClientSecret: "YOUR_CLIENT_SECRET",
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  "https://example.com/auth",
+				TokenURL: "https://example.com/token",
+			},
+			Scopes: []string{"openid"},
+		})
+		if len(warnings) > 0 {
+			t.Errorf("warnings wants empty but was %+v", warnings)
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		warnings := ValidateOAuth2Config(oauth2.Config{RedirectURL: "http://localhost:8000"})
+		var fields []string
+		var severities []Severity
+		for _, w := range warnings {
+			fields = append(fields, w.Field)
+			severities = append(severities, w.Severity)
+		}
+		if diff := cmp.Diff([]string{"ClientID", "Endpoint.AuthURL", "Endpoint.TokenURL", "RedirectURL", "ClientSecret", "Scopes"}, fields); diff != "" {
+			t.Errorf("fields mismatch (-want +got):\n%s", diff)
+		}
+		want := []Severity{SeverityError, SeverityError, SeverityError, SeverityWarning, SeverityWarning, SeverityInfo}
+		if diff := cmp.Diff(want, severities); diff != "" {
+			t.Errorf("severities mismatch (-want +got):\n%s", diff)
+		}
+	})
+}